@@ -6,6 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/casbin/casbin/v2/model"
 	"github.com/casbin/casbin/v2/persist"
@@ -19,12 +23,90 @@ var (
 	_ persist.ContextAdapter          = (*Adapter)(nil)
 	_ persist.ContextBatchAdapter     = (*Adapter)(nil)
 	_ persist.ContextUpdatableAdapter = (*Adapter)(nil)
+	_ persist.FilteredAdapter         = (*Adapter)(nil)
+	_ persist.ContextFilteredAdapter  = (*Adapter)(nil)
 )
 
+// updateBatchSize bounds the number of rules rewritten by a single UPDATE
+// statement in UpdatePolicies.
+const updateBatchSize = 100
+
+// createTableAttempts bounds the attempts of table creation on a busy
+// database when WithRetry is not used, and createTableBackoff returns the
+// delay before each retry.
+const createTableAttempts = 5
+
+func createTableBackoff(attempt int) time.Duration {
+	return time.Duration(attempt) * 20 * time.Millisecond
+}
+
+// createTableMu serializes table creation within the process, so adapters
+// constructed concurrently on the same database do not race on the DDL.
+var createTableMu sync.Mutex
+
 // Adapter represents the Bun adapter for policy storage.
+//
+// An Adapter is safe for concurrent use by multiple goroutines, as every
+// method runs its statements on the database pool. Adapters may also be
+// constructed concurrently on the same database: table and index creation
+// tolerate objects created by another adapter, in this process or another.
+// The filtered state reported by IsFiltered is not synchronized and should
+// only be read by the goroutine loading the policy.
 type Adapter struct {
-	db              *bun.DB
-	notCreateTables bool
+	db                *bun.DB
+	readDB            *bun.DB
+	tableName         string
+	createTables      bool
+	compositeKey      bool
+	uuidKey           bool
+	idGenerator       func() interface{}
+	saveStrategy      SaveStrategy
+	updateStrategy    UpdateStrategy
+	filtered          bool
+	maxAttempts       int
+	backoff           func(attempt int) time.Duration
+	metrics           Metrics
+	events            chan<- PolicyEvent
+	columnType        string
+	columnLength      int
+	collation         string
+	ptypeIndex        bool
+	caseInsensitive   bool
+	metadataColumn    string
+	preserveIDs       bool
+	nullTrailing      bool
+	refuseEmptySave   bool
+	timeouts          map[string]time.Duration
+	cache             *loadCache
+	uniqueColumns     []string
+	noUniqueIndex     bool
+	newRow            func() PolicyRow
+	priorityColumn    string
+	versionColumn     string
+	priorityField     int
+	ptypes            []string
+	autoRecreateTable bool
+	beforeMutation    MutationHook
+	afterMutation     MutationHook
+	afterLoad         func(model model.Model) error
+	allowEmptyRules   bool
+	deleteBatchSize   int
+	readOnlyLoads     bool
+	rowValidator      RowValidator
+	requestID         func(ctx context.Context) string
+	buffer            *writeBuffer
+	errorHandler      func(err error)
+	ptypeTables       map[string]string
+	ctx               context.Context
+	txOpts            sql.TxOptions
+
+	// tx is the transaction bound by WithTx, and root the adapter it was
+	// bound from, kept reachable as its finalizer closes the database.
+	tx   *bun.Tx
+	root *Adapter
+
+	// optionErr collects the invalid options, reported by NewAdapter.
+	optionErr error
 }
 
 // CasbinBunOption defines a functional option type for configuring a BunAdapter.
@@ -37,14 +119,77 @@ type CasbinBunOption func(*Adapter)
 //
 // Example:
 //
-//	adapter, err := NewAdapter(db, DisableAutoCreateTable())
+//	adapter, err := NewAdapter(ctx, db, DisableAutoCreateTable())
 func DisableAutoCreateTable() CasbinBunOption {
 	return func(a *Adapter) {
-		a.notCreateTables = true
+		a.createTables = false
+	}
+}
+
+// WithAutoCreateTable creates the Casbin policy storage table and its indexes
+// during adapter initialization if they do not exist yet. This is the default,
+// and the option makes it explicit. The last of WithAutoCreateTable and
+// DisableAutoCreateTable wins.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithAutoCreateTable())
+func WithAutoCreateTable() CasbinBunOption {
+	return func(a *Adapter) {
+		a.createTables = true
+	}
+}
+
+// WithReadDB routes the queries that only read policies, such as LoadPolicy,
+// to readDB, while every mutation keeps using the primary database.
+// This lets read-heavy deployments load policies from a replica.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, primary, WithReadDB(replica))
+func WithReadDB(readDB *bun.DB) CasbinBunOption {
+	return func(a *Adapter) {
+		a.readDB = readDB
+	}
+}
+
+// WithContext sets the context used by the methods without a context
+// parameter, such as LoadPolicy or AddPolicy, which Casbin calls through its
+// legacy interfaces. Cancelling ctx, e.g. on shutdown, then aborts these
+// calls too. The default is context.Background().
+//
+// Example:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+//	defer stop()
+//	adapter, err := NewAdapter(ctx, db, WithContext(ctx))
+func WithContext(ctx context.Context) CasbinBunOption {
+	return func(a *Adapter) {
+		if ctx == nil {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: context must not be nil"))
+			return
+		}
+		a.ctx = ctx
+	}
+}
+
+// WithAllowEmptyRules lets the adapter add rules without any non-empty value,
+// which are otherwise rejected with ErrEmptyRule. Such a rule is stored as a
+// row holding only its ptype, and is matched by every filtered removal of the
+// ptype.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithAllowEmptyRules())
+func WithAllowEmptyRules() CasbinBunOption {
+	return func(a *Adapter) {
+		a.allowEmptyRules = true
 	}
 }
 
 // NewAdapter creates a new Casbin policy adapter using a Bun database connection.
+// It fails with an error wrapping ErrUnsupportedDialect if db has no dialect
+// or one the adapter does not support.
 //
 // Example:
 //
@@ -55,18 +200,96 @@ func DisableAutoCreateTable() CasbinBunOption {
 //	}
 //	enforcer, err := casbin.NewEnforcer("model.conf", adapter)
 func NewAdapter(ctx context.Context, db *bun.DB, opts ...CasbinBunOption) (*Adapter, error) {
+	b, err := NewAdapterWithoutInit(db, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.createTables {
+		if err := b.Init(ctx); err != nil {
+			// The caller keeps db, which the discarded adapter must not close.
+			runtime.SetFinalizer(b, nil)
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// NewAdapterWithoutInit creates a new Casbin policy adapter like NewAdapter,
+// without any database I/O. The policy table is not created until Init is
+// called, which lets adapters be constructed where no context is available,
+// such as during package initialization.
+//
+// Example:
+//
+//	var adapter, _ = casbun.NewAdapterWithoutInit(db)
+//
+//	func main() {
+//	    if err := adapter.Init(context.Background()); err != nil {
+//	        log.Fatal("Failed to create policy table:", err)
+//	    }
+//	}
+func NewAdapterWithoutInit(db *bun.DB, opts ...CasbinBunOption) (*Adapter, error) {
+	if err := checkDialect(db); err != nil {
+		return nil, err
+	}
+
 	b := &Adapter{
-		db: db,
+		db:           db,
+		tableName:    defaultTableName,
+		createTables: true,
+		metrics:      noopMetrics{},
+		ctx:          context.Background(),
+
+		columnType:   defaultColumnType,
+		columnLength: defaultColumnLength,
+		ptypeIndex:   true,
+
+		priorityField: -1,
 	}
 
 	for _, opt := range opts {
 		opt(b)
 	}
-
-	if !b.notCreateTables {
-		if err := b.createTable(ctx); err != nil {
-			return nil, err
-		}
+	if b.uuidKey && b.idGenerator == nil {
+		b.idGenerator = newUUID
+	}
+	if b.compositeKey && b.idGenerator != nil {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: a composite key can not be combined with a UUID key or an id generator"))
+	}
+	if b.preserveIDs && !b.hasSerialID() {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: ids can only be preserved with an autoincrement id"))
+	}
+	if b.compositeKey && b.noUniqueIndex {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: the unique index can not be disabled with a composite key"))
+	}
+	if b.compositeKey && b.uniqueColumns != nil {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: unique columns can not be combined with a composite key"))
+	}
+	if b.nullTrailing && !b.hasSerialID() {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: NULL trailing values require an autoincrement id"))
+	}
+	if b.newRow != nil && (!b.hasSerialID() || b.nullTrailing || b.preserveIDs) {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: a custom model requires the default autoincrement id and rows"))
+	}
+	if b.priorityField >= 0 && b.priorityColumn == "" {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: a priority field requires a priority column"))
+	}
+	if b.collation != "" && b.newRow != nil {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: a collation can not be combined with a custom model"))
+	}
+	if b.priorityColumn != "" && b.newRow != nil {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: a priority column can not be combined with a custom model"))
+	}
+	if b.compositeKey && b.deleteBatchSize > 0 {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: a delete batch size requires an id column"))
+	}
+	if b.ptypeTables != nil && b.buffer != nil {
+		b.optionErr = errors.Join(b.optionErr, errors.New("casbun: ptype tables can not be combined with a write buffer"))
+	}
+	if b.optionErr != nil {
+		return nil, b.optionErr
 	}
 
 	runtime.SetFinalizer(b, func(a *Adapter) {
@@ -78,58 +301,308 @@ func NewAdapter(ctx context.Context, db *bun.DB, opts ...CasbinBunOption) (*Adap
 	return b, nil
 }
 
+// Init creates the policy table and its indexes if they do not exist yet,
+// along with the tables set by WithPTypeTable. NewAdapter calls it unless
+// DisableAutoCreateTable is used, so it is only needed with
+// NewAdapterWithoutInit.
+func (a *Adapter) Init(ctx context.Context) (err error) {
+	defer wrapOpError("init", "", &err)
+	for _, r := range a.routes() {
+		if err := r.createTable(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTable creates the policy table and its indexes. A busy database, e.g.
+// a SQLite file locked by another process, is retried like with WithRetry, and
+// with createTableAttempts and createTableBackoff if WithRetry is not used, so
+// that adapters created concurrently do not fail.
 func (a *Adapter) createTable(ctx context.Context) error {
-	tx, err := a.db.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return err
+	op := func(ctx context.Context) error {
+		createTableMu.Lock()
+		defer createTableMu.Unlock()
+
+		return a.conn(ctx).RunInTx(ctx, a.txOptions(), func(ctx context.Context, tx bun.Tx) error {
+			if err := a.createBareTable(ctx, tx); err != nil {
+				return err
+			}
+			return a.createIndexes(ctx, tx)
+		})
 	}
-	if _, err := tx.NewCreateTable().
-		Model((*CasbinPolicy)(nil)).
-		IfNotExists().
-		Exec(ctx); err != nil {
-		return errors.Join(err, tx.Rollback())
+	if _, ok := a.txFor(ctx); ok {
+		return op(ctx)
 	}
 
-	if _, err := tx.NewRaw(
-		"CREATE UNIQUE INDEX unique_casbin_policy on casbin_policies (ptype, v0, v1, v2, v3, v4, v5)",
-	).Exec(ctx); err != nil {
-		return errors.Join(err, tx.Rollback())
+	maxAttempts, backoff := a.maxAttempts, a.backoff
+	if maxAttempts <= 1 {
+		maxAttempts, backoff = createTableAttempts, createTableBackoff
 	}
+	return retryAttempts(ctx, maxAttempts, backoff, op)
+}
 
-	if _, err := tx.NewRaw("CREATE INDEX idx_casbin_ptype ON casbin_policies (ptype)").Exec(ctx); err != nil {
-		return errors.Join(err, tx.Rollback())
+// createBareTable creates the policy table on tx without its indexes, unless
+// it already exists.
+func (a *Adapter) createBareTable(ctx context.Context, tx bun.Tx) error {
+	if a.compositeKey {
+		_, err := tx.NewRaw(a.createCompositeTableQuery(), bun.Ident(a.tableName)).
+			Comment(a.queryComment(ctx)).
+			Exec(ctx)
+		return err
 	}
 
-	return tx.Commit()
+	_, err := a.newCreateTable(tx).
+		Comment(a.queryComment(ctx)).
+		Exec(ctx)
+	return err
+}
+
+// createIndexes creates the indexes of the policy table on tx, unless they
+// already exist.
+func (a *Adapter) createIndexes(ctx context.Context, tx bun.Tx) error {
+	// The primary key already covers both indexes.
+	if a.compositeKey {
+		return nil
+	}
+
+	if !a.noUniqueIndex {
+		if err := a.createUniqueIndex(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if a.ptypeIndex {
+		_, ptypeIndex := a.indexNames()
+		return a.createIndex(ctx, tx, "CREATE INDEX", ptypeIndex, "ptype")
+	}
+	return nil
 }
 
 // LoadPolicy loads all policy rules from the storage.
 func (a *Adapter) LoadPolicy(model model.Model) error {
-	return a.LoadPolicyCtx(context.Background(), model)
+	ctx, cancel := a.operationContext("load_policy")
+	defer cancel()
+	return a.LoadPolicyCtx(ctx, model)
 }
 
 // LoadPolicyCtx loads all policy rules from the storage with context.
-func (a *Adapter) LoadPolicyCtx(ctx context.Context, model model.Model) error {
+func (a *Adapter) LoadPolicyCtx(ctx context.Context, model model.Model) (err error) {
+	defer wrapOpError("load_policy", "", &err)
+	defer a.observe("load_policy")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return err
+	}
+
+	if a.cache != nil {
+		err = a.loadCachedPolicy(ctx, model)
+	} else {
+		err = a.loadPolicy(ctx, model, Filter{})
+	}
+	if err != nil {
+		return err
+	}
+	if err := a.runAfterLoad(model); err != nil {
+		return err
+	}
+
+	a.filtered = false
+	return nil
+}
+
+func (a *Adapter) loadPolicy(ctx context.Context, model model.Model, filter Filter) error {
+	return a.loadPolicyWithQuery(ctx, model, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return filter.apply(q, a.caseInsensitive)
+	})
+}
+
+// loadPolicyWithQuery adds to model the policies selected by the base select
+// query as modified by apply.
+func (a *Adapter) loadPolicyWithQuery(
+	ctx context.Context,
+	model model.Model,
+	apply func(*bun.SelectQuery) *bun.SelectQuery,
+) error {
+	if err := a.flushWrites(ctx); err != nil {
+		return err
+	}
+
 	var policies []CasbinPolicy
-	err := a.db.NewSelect().
-		Model(&policies).
-		Scan(ctx)
+	err := a.readOnlyLoad(ctx, func(ctx context.Context) error {
+		for _, r := range a.routes() {
+			routed, err := r.selectLoadedPolicies(ctx, apply)
+			if err != nil {
+				return err
+			}
+			policies = append(policies, routed...)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
+	return a.loadPolicyRecords(policies, model)
+}
+
+// selectLoadedPolicies returns the policies of the table selected by the base
+// select query as modified by apply.
+func (a *Adapter) selectLoadedPolicies(
+	ctx context.Context,
+	apply func(*bun.SelectQuery) *bun.SelectQuery,
+) ([]CasbinPolicy, error) {
+	if a.newRow != nil {
+		rows := a.newModelRows(nil)
+		if err := a.loadQuery(ctx, apply, rows).Scan(ctx); err != nil {
+			return nil, tableError(err)
+		}
+		return modelRowPolicies(rows), nil
+	}
+
+	var policies []CasbinPolicy
+	if err := a.loadQuery(ctx, apply, &policies).Scan(ctx); err != nil {
+		return nil, tableError(err)
+	}
+	return policies, nil
+}
+
+// loadQuery returns the query selecting the loaded policy rows into dest, as
+// modified by apply.
+func (a *Adapter) loadQuery(
+	ctx context.Context,
+	apply func(*bun.SelectQuery) *bun.SelectQuery,
+	dest interface{},
+) *bun.SelectQuery {
+	query := apply(a.newSelect(ctx, a.reader(ctx), dest)).
+		ApplyQueryBuilder(a.allowedPTypes)
+	if a.priorityColumn != "" {
+		query = a.orderedSelect(query)
+	}
+	return query
+}
+
+// loadPolicyRecords adds policies to model.
+func (a *Adapter) loadPolicyRecords(policies []CasbinPolicy, model model.Model) error {
 	for _, policy := range policies {
 		if err := loadPolicyRecord(policy, model); err != nil {
 			return err
 		}
 	}
 
+	a.metrics.SetPolicyCount(len(policies))
 	return nil
 }
 
+// LoadPolicyStream loads all policy rules from the storage like LoadPolicyCtx,
+// but reads them from a cursor one row at a time instead of materializing the
+// whole table first. This bounds the memory used to load very large policy sets.
+// Rows are read in id order, or in key order when WithCompositeKey is used,
+// after the priority order when WithPriorityColumn is used.
+func (a *Adapter) LoadPolicyStream(ctx context.Context, model model.Model) (err error) {
+	defer wrapOpError("load_policy_stream", "", &err)
+	defer a.observe("load_policy_stream")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return err
+	}
+
+	count := 0
+	err = a.readOnlyLoad(ctx, func(ctx context.Context) error {
+		for _, r := range a.routes() {
+			n, err := r.streamPolicy(ctx, model)
+			if err != nil {
+				return err
+			}
+			count += n
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	a.metrics.SetPolicyCount(count)
+	if err := a.runAfterLoad(model); err != nil {
+		return err
+	}
+
+	a.filtered = false
+	return nil
+}
+
+// streamPolicy loads the policy rules of the table into model one row at a
+// time, and returns the number of rules it read.
+func (a *Adapter) streamPolicy(ctx context.Context, model model.Model) (count int, err error) {
+	rows, err := a.orderedSelect(a.newSelect(ctx, a.reader(ctx), (*CasbinPolicy)(nil))).
+		ApplyQueryBuilder(a.allowedPTypes).
+		Rows(ctx)
+	if err != nil {
+		return 0, tableError(err)
+	}
+	defer func() {
+		err = errors.Join(err, rows.Close())
+	}()
+
+	for rows.Next() {
+		var policy CasbinPolicy
+		if err := a.db.ScanRow(ctx, rows, &policy); err != nil {
+			return count, err
+		}
+		if err := loadPolicyRecord(policy, model); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// LoadPolicyArray returns every stored rule grouped by ptype, without building
+// a model, for caching layers that keep the raw rules. The rules are ordered
+// like the rules loaded by LoadPolicyCtx.
+//
+// Example:
+//
+//	rules, err := adapter.LoadPolicyArray(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	cache.Store(rules["p"], rules["g"])
+func (a *Adapter) LoadPolicyArray(ctx context.Context) (_ map[string][][]string, err error) {
+	defer wrapOpError("load_policy_array", "", &err)
+	defer a.observe("load_policy_array")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	var policies []CasbinPolicy
+	for _, r := range a.routes() {
+		var routed []CasbinPolicy
+		if err := r.orderedSelect(r.newSelect(ctx, r.reader(ctx), &routed)).
+			ApplyQueryBuilder(r.allowedPTypes).
+			Scan(ctx); err != nil {
+			return nil, tableError(err)
+		}
+		policies = append(policies, routed...)
+	}
+
+	rules := make(map[string][][]string)
+	for _, policy := range policies {
+		rules[policy.PType] = append(rules[policy.PType], policy.filterValues())
+	}
+
+	a.metrics.SetPolicyCount(len(policies))
+	return rules, nil
+}
+
 func loadPolicyRecord(policy CasbinPolicy, model model.Model) error {
 	pType := policy.PType
-	sec := pType[:1]
+	// Rows written by hand may lack a ptype, which maps to no section.
+	if pType == "" {
+		return fmt.Errorf("casbun: stored rule %v (id %d) has an empty ptype", policy.filterValues(), policy.ID)
+	}
+	sec := policySection(model, pType)
 	ok, err := model.HasPolicyEx(sec, pType, policy.filterValues())
 	if err != nil {
 		return err
@@ -140,50 +613,217 @@ func loadPolicyRecord(policy CasbinPolicy, model model.Model) error {
 	return model.AddPolicy(sec, pType, policy.filterValues())
 }
 
+// policySection returns the section of model defining ptype, such as p for
+// p2. Ptypes missing from the model fall back to the section named after
+// their first letter.
+func policySection(model model.Model, ptype string) string {
+	for sec, assertions := range model {
+		switch sec {
+		case "r", "e", "m":
+			// These sections define no ptype.
+			continue
+		}
+		if _, ok := assertions[ptype]; ok {
+			return sec
+		}
+	}
+	return ptype[:1]
+}
+
 // SavePolicy saves all policy rules to the storage.
 func (a *Adapter) SavePolicy(model model.Model) error {
-	return a.SavePolicyCtx(context.Background(), model)
+	ctx, cancel := a.operationContext("save_policy")
+	defer cancel()
+	return a.SavePolicyCtx(ctx, model)
 }
 
 // SavePolicyCtx saves all policy rules to the storage with context.
-func (a *Adapter) SavePolicyCtx(ctx context.Context, model model.Model) error {
-	policies := make([]CasbinPolicy, 0, len(model["p"])+len(model["g"]))
+func (a *Adapter) SavePolicyCtx(ctx context.Context, model model.Model) (err error) {
+	defer wrapOpError("save_policy", "", &err)
+	defer a.observe("save_policy")()
 
-	// go through policy definitions
-	for ptype, ast := range model["p"] {
-		for _, rule := range ast.Policy {
-			policies = append(policies, newCasbinPolicy(ptype, rule))
-		}
+	policies := a.allowedPolicies(modelPolicies(model))
+	if len(policies) == 0 && a.refuseEmptySave {
+		return ErrEmptySave
 	}
 
-	// go through role definitions
-	for gtype, ast := range model["g"] {
-		for _, rule := range ast.Policy {
-			policies = append(policies, newCasbinPolicy(gtype, rule))
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.saveRoutedPolicyRecords(ctx, policies)
+	})
+	return a.notify(ctx, err, PolicyEvent{Op: EventSave})
+}
+
+// modelPolicies returns the rules of every section of model holding rules,
+// such as p and g with all their ptypes. The policy sections come first, then
+// the role sections, then any other section.
+func modelPolicies(model model.Model) []CasbinPolicy {
+	sections := make([]string, 0, len(model))
+	for sec := range model {
+		sections = append(sections, sec)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sectionOrder(sections[i]) < sectionOrder(sections[j]) ||
+			sectionOrder(sections[i]) == sectionOrder(sections[j]) && sections[i] < sections[j]
+	})
+
+	policies := make([]CasbinPolicy, 0)
+	for _, sec := range sections {
+		ptypes := make([]string, 0, len(model[sec]))
+		for ptype := range model[sec] {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+
+		for _, ptype := range ptypes {
+			for _, rule := range model[sec][ptype].Policy {
+				policies = append(policies, newCasbinPolicy(ptype, rule))
+			}
 		}
 	}
+	return policies
+}
 
-	return a.savePolicyRecords(ctx, policies)
+// sectionOrder ranks sec for modelPolicies.
+func sectionOrder(sec string) int {
+	switch sec {
+	case "p":
+		return 0
+	case "g":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// saveRoutedPolicyRecords replaces the stored policy with policies, in the
+// tables set by WithPTypeTable if any.
+func (a *Adapter) saveRoutedPolicyRecords(ctx context.Context, policies []CasbinPolicy) error {
+	routes, grouped := a.routePolicies(policies)
+	for i, r := range routes {
+		if err := r.savePolicyRecords(ctx, grouped[i]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (a *Adapter) savePolicyRecords(ctx context.Context, policies []CasbinPolicy) error {
+	switch a.saveStrategy {
+	case SaveUpsert:
+		return a.upsertPolicyRecords(ctx, policies)
+	case SaveSwap:
+		return a.swapPolicyRecords(ctx, policies)
+	}
+
+	if a.preserveIDs {
+		return a.savePolicyRecordsWithIDs(ctx, policies)
+	}
+
 	if err := a.refreshTable(ctx); err != nil {
 		return err
 	}
 
-	if _, err := a.db.NewInsert().
-		Model(&policies).
+	if _, err := a.newInsert(ctx, a.conn(ctx), &policies).
 		Exec(ctx); err != nil {
+		return insertError(err)
+	}
+
+	return nil
+}
+
+// savePolicyRecordsWithIDs replaces the stored policy with policies like
+// savePolicyRecords, keeping the ids of the rules already stored. The rules
+// keeping their id are inserted first, so that the ids assigned to the new
+// rules can not collide with them.
+func (a *Adapter) savePolicyRecordsWithIDs(ctx context.Context, policies []CasbinPolicy) error {
+	var stored []CasbinPolicy
+	if err := a.newSelect(ctx, a.conn(ctx), &stored).
+		ApplyQueryBuilder(a.allowedPTypes).
+		Scan(ctx); err != nil {
+		return err
+	}
+	ids := make(map[[7]string]int64, len(stored))
+	for _, policy := range stored {
+		ids[policy.key()] = policy.ID
+	}
+
+	kept := make([]CasbinPolicy, 0, len(policies))
+	added := make([]CasbinPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if id, ok := ids[policy.key()]; ok {
+			policy.ID = id
+			kept = append(kept, policy)
+		} else {
+			added = append(added, policy)
+		}
+	}
+
+	if err := a.refreshTable(ctx); err != nil {
 		return err
 	}
 
+	for _, batch := range [][]CasbinPolicy{kept, added} {
+		if len(batch) == 0 {
+			continue
+		}
+		if _, err := a.newInsert(ctx, a.conn(ctx), &batch).
+			Exec(ctx); err != nil {
+			return insertError(err)
+		}
+		if err := a.resetIDSequence(ctx, a.conn(ctx)); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// refreshTable truncates the table.
+// Clear removes every policy rule from the storage.
+// Unlike SavePolicy it does not need a model, which makes it suitable for
+// administrative tooling such as resetting a test environment.
+//
+// Clear honors the save strategy: the table is truncated with SaveTruncate,
+// while with SaveUpsert and SaveSwap the rows are removed by a single DELETE,
+// which is transactional on every dialect.
+func (a *Adapter) Clear(ctx context.Context) (err error) {
+	defer wrapOpError("clear", "", &err)
+	defer a.observe("clear")()
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		for _, r := range a.routes() {
+			if err := r.clearTable(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return a.notify(ctx, err, PolicyEvent{Op: EventClear})
+}
+
+// clearTable removes every policy rule from the table, see Clear.
+func (a *Adapter) clearTable(ctx context.Context) error {
+	if a.saveStrategy != SaveTruncate {
+		_, err := a.newDelete(ctx, a.conn(ctx)).
+			Where("1 = 1").
+			ApplyQueryBuilder(a.allowedPTypes).
+			Exec(ctx)
+		return err
+	}
+
+	return a.refreshTable(ctx)
+}
+
+// refreshTable truncates the table, or deletes the rows of the ptypes allowed
+// by WithPTypeFilter.
 func (a *Adapter) refreshTable(ctx context.Context) error {
-	if _, err := a.db.NewTruncateTable().
-		Model((*CasbinPolicy)(nil)).
+	if a.ptypes != nil {
+		_, err := a.newDelete(ctx, a.conn(ctx)).
+			ApplyQueryBuilder(a.allowedPTypes).
+			Exec(ctx)
+		return err
+	}
+
+	if _, err := a.newTruncate(ctx, a.conn(ctx)).
 		Exec(ctx); err != nil {
 		return err
 	}
@@ -193,143 +833,613 @@ func (a *Adapter) refreshTable(ctx context.Context) error {
 // AddPolicy adds a policy rule to the storage.
 // This is part of the Auto-Save feature.
 func (a *Adapter) AddPolicy(sec, ptype string, rule []string) error {
-	return a.AddPolicyCtx(context.Background(), sec, ptype, rule)
+	ctx, cancel := a.operationContext("add_policy")
+	defer cancel()
+	return a.AddPolicyCtx(ctx, sec, ptype, rule)
 }
 
 // AddPolicyCtx adds a policy rule to the storage with context.
 // This is part of the Auto-Save feature.
-func (a *Adapter) AddPolicyCtx(ctx context.Context, _, ptype string, rule []string) error {
+func (a *Adapter) AddPolicyCtx(ctx context.Context, _, ptype string, rule []string) (err error) {
+	defer wrapOpError("add_policy", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.AddPolicyCtx(ctx, "", ptype, rule)
+	}
+	defer a.observe("add_policy")()
+
+	if err := a.checkRules(ptype, [][]string{rule}); err != nil {
+		return err
+	}
+	event := PolicyEvent{Op: EventAdd, PType: ptype, Rules: [][]string{rule}}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
 	newPolicy := newCasbinPolicy(ptype, rule)
-	if _, err := a.db.NewInsert().
-		Model(&newPolicy).
-		Exec(ctx); err != nil {
+	if buffered, err := a.bufferPolicy(ctx, newPolicy); buffered {
 		return err
 	}
-	return nil
+	err = a.retry(ctx, func(ctx context.Context) error {
+		if _, err := a.newInsert(ctx, a.conn(ctx), &newPolicy).
+			Exec(ctx); err != nil {
+			return insertError(err)
+		}
+		return nil
+	})
+	return a.notify(ctx, err, event)
 }
 
 // AddPolicies adds policy rules to the storage.
 // This is part of the Auto-Save feature.
 func (a *Adapter) AddPolicies(sec, ptype string, rules [][]string) error {
-	return a.AddPoliciesCtx(context.Background(), sec, ptype, rules)
+	ctx, cancel := a.operationContext("add_policies")
+	defer cancel()
+	return a.AddPoliciesCtx(ctx, sec, ptype, rules)
 }
 
-// AddPoliciesCtx adds policy rules to the storage.
-// This is part of the Auto-Save feature.
-func (a *Adapter) AddPoliciesCtx(ctx context.Context, _, ptype string, rules [][]string) error {
-	policies := make([]CasbinPolicy, 0, len(rules))
-	for _, rule := range rules {
-		policies = append(policies, newCasbinPolicy(ptype, rule))
+// AddPoliciesCtx adds policy rules to the storage.
+// This is part of the Auto-Save feature.
+//
+// The rules are inserted in batches within a single transaction, so either
+// all of them are added or none.
+func (a *Adapter) AddPoliciesCtx(ctx context.Context, _, ptype string, rules [][]string) (err error) {
+	defer wrapOpError("add_policies", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.AddPoliciesCtx(ctx, "", ptype, rules)
+	}
+	defer a.observe("add_policies")()
+
+	if err := a.checkRules(ptype, rules); err != nil {
+		return err
+	}
+	event := PolicyEvent{Op: EventAdd, PType: ptype, Rules: rules}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
+	policies := make([]CasbinPolicy, 0, len(rules))
+	for _, rule := range rules {
+		policies = append(policies, newCasbinPolicy(ptype, rule))
+	}
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				for start := 0; start < len(policies); start += insertBatchSize {
+					batch := policies[start:min(start+insertBatchSize, len(policies))]
+					if _, err := a.newInsert(ctx, tx, &batch).
+						Exec(ctx); err != nil {
+						return insertError(err)
+					}
+				}
+				return nil
+			},
+		)
+	})
+	return a.notify(ctx, err, event)
+}
+
+// AddPoliciesIgnoreExisting adds the rules of ptype that are not stored yet,
+// skipping the existing ones instead of failing with ErrPolicyExists, and
+// returns the number of rules added. This makes bootstrap scripts idempotent.
+// It is supported on PostgreSQL, SQLite and MySQL.
+//
+// Example:
+//
+//	added, err := adapter.AddPoliciesIgnoreExisting(ctx, "p", defaultRules)
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("added %d default rules", added)
+func (a *Adapter) AddPoliciesIgnoreExisting(ctx context.Context, ptype string, rules [][]string) (_ int64, err error) {
+	defer wrapOpError("add_policies_ignore_existing", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.AddPoliciesIgnoreExisting(ctx, ptype, rules)
+	}
+	defer a.observe("add_policies_ignore_existing")()
+
+	if err := a.checkRules(ptype, rules); err != nil {
+		return 0, err
+	}
+	event := PolicyEvent{Op: EventAdd, PType: ptype, Rules: rules}
+	if err := a.before(ctx, event); err != nil {
+		return 0, err
+	}
+
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	policies := make([]CasbinPolicy, 0, len(rules))
+	for _, rule := range rules {
+		policies = append(policies, newCasbinPolicy(ptype, rule))
+	}
+
+	var added int64
+	err = a.retry(ctx, func(ctx context.Context) error {
+		query, err := a.newInsertIgnore(ctx, a.conn(ctx), &policies)
+		if err != nil {
+			return err
+		}
+		res, err := query.Exec(ctx)
+		if err != nil {
+			return err
+		}
+		added, err = res.RowsAffected()
+		return err
+	})
+	if err := a.notify(ctx, err, event); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+// RemovePolicy removes a policy rule from the storage.
+// This is part of the Auto-Save feature.
+func (a *Adapter) RemovePolicy(sec, ptype string, rule []string) error {
+	ctx, cancel := a.operationContext("remove_policy")
+	defer cancel()
+	return a.RemovePolicyCtx(ctx, sec, ptype, rule)
+}
+
+// RemovePolicyCtx removes a policy rule from the storage with context.
+// This is part of the Auto-Save feature.
+func (a *Adapter) RemovePolicyCtx(ctx context.Context, _, ptype string, rule []string) (err error) {
+	defer wrapOpError("remove_policy", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.RemovePolicyCtx(ctx, "", ptype, rule)
+	}
+	defer a.observe("remove_policy")()
+
+	event := PolicyEvent{Op: EventRemove, PType: ptype, Rules: [][]string{rule}}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
+	exisingPolicy := newCasbinPolicy(ptype, rule)
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.deleteRecord(ctx, exisingPolicy)
+	})
+	return a.notify(ctx, err, event)
+}
+
+// RemovePolicyExists removes a policy rule from the storage like
+// RemovePolicyCtx, and reports whether the rule was stored. Removing a rule
+// that is already absent is not an error, which lets callers detect stale
+// delete attempts.
+//
+// Example:
+//
+//	removed, err := adapter.RemovePolicyExists(ctx, "p", []string{"alice", "data1", "read"})
+//	if err == nil && !removed {
+//	    log.Printf("rule was already removed")
+//	}
+func (a *Adapter) RemovePolicyExists(ctx context.Context, ptype string, rule []string) (_ bool, err error) {
+	defer wrapOpError("remove_policy_exists", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.RemovePolicyExists(ctx, ptype, rule)
+	}
+	defer a.observe("remove_policy_exists")()
+
+	event := PolicyEvent{Op: EventRemove, PType: ptype, Rules: [][]string{rule}}
+	if err := a.before(ctx, event); err != nil {
+		return false, err
+	}
+
+	var removed int64
+	err = a.retry(ctx, func(ctx context.Context) error {
+		res, err := a.newDelete(ctx, a.conn(ctx)).
+			ApplyQueryBuilder(keyCondition(newCasbinPolicy(ptype, rule))).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		removed, err = res.RowsAffected()
+		return err
+	})
+	if err := a.notify(ctx, err, event); err != nil {
+		return false, err
+	}
+	return removed > 0, nil
+}
+
+// RemovePolicies removes policy rules from the storage.
+// This is part of the Auto-Save feature.
+func (a *Adapter) RemovePolicies(sec, ptype string, rules [][]string) error {
+	ctx, cancel := a.operationContext("remove_policies")
+	defer cancel()
+	return a.RemovePoliciesCtx(ctx, sec, ptype, rules)
+}
+
+// RemovePoliciesCtx removes policy rules from the storage.
+// This is part of the Auto-Save feature.
+func (a *Adapter) RemovePoliciesCtx(ctx context.Context, _, ptype string, rules [][]string) (err error) {
+	defer wrapOpError("remove_policies", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.RemovePoliciesCtx(ctx, "", ptype, rules)
+	}
+	defer a.observe("remove_policies")()
+
+	event := PolicyEvent{Op: EventRemove, PType: ptype, Rules: rules}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				for _, rule := range rules {
+					exisingPolicy := newCasbinPolicy(ptype, rule)
+					if err := a.deleteRecordInTx(ctx, tx, exisingPolicy); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		)
+	})
+	return a.notify(ctx, err, event)
+}
+
+func (a *Adapter) deleteRecord(ctx context.Context, existingPolicy CasbinPolicy) error {
+	return a.delete(ctx, a.newDelete(ctx, a.conn(ctx)), existingPolicy)
+}
+
+func (a *Adapter) deleteRecordInTx(
+	ctx context.Context,
+	tx bun.Tx,
+	existingPolicy CasbinPolicy,
+) error {
+	return a.delete(ctx, a.newDelete(ctx, tx), existingPolicy)
+}
+
+// delete runs query, deleting the row storing policy. The rule is matched
+// positionally on every column, so a rule with an empty field does not match
+// a rule holding a value there.
+func (a *Adapter) delete(
+	ctx context.Context,
+	query *bun.DeleteQuery,
+	policy CasbinPolicy,
+) error {
+	if _, err := query.ApplyQueryBuilder(keyCondition(policy)).Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RemoveFilteredPolicy removes policy rules that match the filter from the storage.
+// This is part of the Auto-Save feature.
+// This API is explained in the link below:
+// https://casbin.org/docs/management-api/#removefilteredpolicy
+func (a *Adapter) RemoveFilteredPolicy(
+	sec, ptype string,
+	fieldIndex int,
+	fieldValues ...string,
+) error {
+	ctx, cancel := a.operationContext("remove_filtered_policy")
+	defer cancel()
+	return a.RemoveFilteredPolicyCtx(ctx, sec, ptype, fieldIndex, fieldValues...)
+}
+
+// RemoveFilteredPolicyCtx removes policy rules that match the filter from the storage with context.
+// This is part of the Auto-Save feature.
+func (a *Adapter) RemoveFilteredPolicyCtx(
+	ctx context.Context,
+	sec, ptype string,
+	fieldIndex int,
+	fieldValues ...string,
+) (err error) {
+	defer wrapOpError("remove_filtered_policy", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.RemoveFilteredPolicyCtx(ctx, sec, ptype, fieldIndex, fieldValues...)
+	}
+	defer a.observe("remove_filtered_policy")()
+
+	event := PolicyEvent{
+		Op:          EventRemoveFiltered,
+		PType:       ptype,
+		FieldIndex:  fieldIndex,
+		FieldValues: fieldValues,
+	}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.deleteFilteredPolicy(ctx, ptype, fieldIndex, fieldValues...)
+	})
+	return a.notify(ctx, err, event)
+}
+
+// RemoveFilteredPolicyReturning removes the rules matched like
+// RemoveFilteredPolicyCtx and returns them, for logging or to undo the
+// removal. The rules are selected and deleted in the same transaction, and
+// each is returned with its ptype first, like the rules returned by
+// UpdateFilteredPolicies.
+//
+// Example:
+//
+//	removed, err := adapter.RemoveFilteredPolicyReturning(ctx, "p", 0, "alice")
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("removed %v", removed)
+func (a *Adapter) RemoveFilteredPolicyReturning(
+	ctx context.Context,
+	ptype string,
+	fieldIndex int,
+	fieldValues ...string,
+) (_ [][]string, err error) {
+	defer wrapOpError("remove_filtered_policy_returning", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.RemoveFilteredPolicyReturning(ctx, ptype, fieldIndex, fieldValues...)
+	}
+	defer a.observe("remove_filtered_policy_returning")()
+
+	condition, err := a.filteredCondition(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
+	}
+	event := PolicyEvent{
+		Op:          EventRemoveFiltered,
+		PType:       ptype,
+		FieldIndex:  fieldIndex,
+		FieldValues: fieldValues,
+	}
+	if err := a.before(ctx, event); err != nil {
+		return nil, err
+	}
+
+	var out [][]string
+	err = a.retry(ctx, func(ctx context.Context) error {
+		// Without new rules, the update only removes the matched rules.
+		out, err = a.updateFilteredPolicies(ctx, condition, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range out {
+		event.Rules = append(event.Rules, rule[1:])
+	}
+	return out, a.notify(ctx, nil, event)
+}
+
+// RemoveAllByPType removes every rule of ptype from the storage with a single
+// statement, and returns the number of rules removed. Unlike
+// RemoveFilteredPolicyCtx without field values, the rules are matched on their
+// ptype alone.
+//
+// Example:
+//
+//	// Drop every role assignment before migrating them.
+//	removed, err := adapter.RemoveAllByPType(ctx, "g")
+func (a *Adapter) RemoveAllByPType(ctx context.Context, ptype string) (_ int64, err error) {
+	defer wrapOpError("remove_all_by_ptype", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.RemoveAllByPType(ctx, ptype)
+	}
+	defer a.observe("remove_all_by_ptype")()
+
+	event := PolicyEvent{Op: EventRemoveFiltered, PType: ptype}
+	if err := a.before(ctx, event); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	err = a.retry(ctx, func(ctx context.Context) error {
+		res, err := a.newDelete(ctx, a.conn(ctx)).
+			Where("? = ?", ptypeColumn, ptype).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		removed, err = res.RowsAffected()
+		return err
+	})
+	if err := a.notify(ctx, err, event); err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// PreviewRemoveFilteredPolicy returns the rules that RemoveFilteredPolicyCtx
+// would remove for the same arguments, without removing them. It runs on the
+// primary database, or on the transaction carried by ctx, so that the preview
+// is not affected by replication lag.
+func (a *Adapter) PreviewRemoveFilteredPolicy(
+	ctx context.Context,
+	ptype string,
+	fieldIndex int,
+	fieldValues ...string,
+) (_ [][]string, err error) {
+	defer wrapOpError("preview_remove_filtered_policy", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.PreviewRemoveFilteredPolicy(ctx, ptype, fieldIndex, fieldValues...)
+	}
+	defer a.observe("preview_remove_filtered_policy")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	policies, err := a.selectFilteredPolicy(ctx, a.conn(ctx), ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		rules = append(rules, policy.filterValues())
+	}
+	return rules, nil
+}
+
+// GetFilteredPolicy returns the stored rules of ptype that match fieldValues
+// from fieldIndex on, with the matching of RemoveFilteredPolicy, without
+// loading them into a model. Each rule is returned with its ptype first, like
+// the rules returned by UpdateFilteredPolicies.
+func (a *Adapter) GetFilteredPolicy(
+	ctx context.Context,
+	ptype string,
+	fieldIndex int,
+	fieldValues ...string,
+) (_ [][]string, err error) {
+	defer wrapOpError("get_filtered_policy", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.GetFilteredPolicy(ctx, ptype, fieldIndex, fieldValues...)
+	}
+	defer a.observe("get_filtered_policy")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
 	}
-	if _, err := a.db.NewInsert().
-		Model(&policies).
-		Exec(ctx); err != nil {
-		return err
+
+	policies, err := a.selectFilteredPolicy(ctx, a.reader(ctx), ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
 	}
-	return nil
-}
 
-// RemovePolicy removes a policy rule from the storage.
-// This is part of the Auto-Save feature.
-func (a *Adapter) RemovePolicy(sec, ptype string, rule []string) error {
-	return a.RemovePolicyCtx(context.Background(), sec, ptype, rule)
+	rules := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		rules = append(rules, policy.toSlice())
+	}
+	return rules, nil
 }
 
-// RemovePolicyCtx removes a policy rule from the storage with context.
-// This is part of the Auto-Save feature.
-func (a *Adapter) RemovePolicyCtx(ctx context.Context, _, ptype string, rule []string) error {
-	exisingPolicy := newCasbinPolicy(ptype, rule)
-	if err := a.deleteRecord(ctx, exisingPolicy); err != nil {
-		return err
+// GetPoliciesPage returns at most limit stored rules of ptype starting at
+// offset, with the total number of rules of ptype, for admin consoles paging
+// through large policies. Rules are ordered by id, or by rule when
+// WithCompositeKey is used, so that consecutive pages neither overlap nor
+// skip rules while the policy is unchanged. Each rule is returned with its
+// ptype first, like the rules returned by GetFilteredPolicy.
+//
+// Example:
+//
+//	rules, total, err := adapter.GetPoliciesPage(ctx, "p", page*50, 50)
+func (a *Adapter) GetPoliciesPage(ctx context.Context, ptype string, offset, limit int) (_ [][]string, _ int64, err error) {
+	defer wrapOpError("get_policies_page", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.GetPoliciesPage(ctx, ptype, offset, limit)
 	}
-	return nil
-}
+	defer a.observe("get_policies_page")()
 
-// RemovePolicies removes policy rules from the storage.
-// This is part of the Auto-Save feature.
-func (a *Adapter) RemovePolicies(sec, ptype string, rules [][]string) error {
-	return a.RemovePoliciesCtx(context.Background(), sec, ptype, rules)
-}
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, 0, err
+	}
 
-// RemovePoliciesCtx removes policy rules from the storage.
-// This is part of the Auto-Save feature.
-func (a *Adapter) RemovePoliciesCtx(ctx context.Context, _, ptype string, rules [][]string) error {
-	return a.db.RunInTx(
-		ctx,
-		&sql.TxOptions{},
-		func(ctx context.Context, tx bun.Tx) error {
-			for _, rule := range rules {
-				exisingPolicy := newCasbinPolicy(ptype, rule)
-				if err := a.deleteRecordInTx(ctx, tx, exisingPolicy); err != nil {
-					return err
-				}
-			}
-			return nil
-		},
-	)
-}
+	if offset < 0 || limit <= 0 {
+		return nil, 0, fmt.Errorf("casbun: invalid page at offset %d with limit %d", offset, limit)
+	}
 
-func (a *Adapter) deleteRecord(ctx context.Context, existingPolicy CasbinPolicy) error {
-	query := a.db.NewDelete().
-		Model((*CasbinPolicy)(nil)).
-		Where("ptype = ?", existingPolicy.PType)
+	order := []string{"id"}
+	if a.compositeKey {
+		order = valueColumns
+	}
 
-	values := existingPolicy.filterValuesWithKey()
+	var policies []CasbinPolicy
+	total, err := a.newSelect(ctx, a.reader(ctx), &policies).
+		Where("? = ?", ptypeColumn, ptype).
+		Order(order...).
+		Offset(offset).
+		Limit(limit).
+		ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return a.delete(ctx, query, values)
+	rules := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		rules = append(rules, policy.toSlice())
+	}
+	return rules, int64(total), nil
 }
 
-func (a *Adapter) deleteRecordInTx(
-	ctx context.Context,
-	tx bun.Tx,
-	existingPolicy CasbinPolicy,
-) error {
-	query := tx.NewDelete().
-		Model((*CasbinPolicy)(nil)).
-		Where("ptype = ?", existingPolicy.PType)
+// ExistingPolicies returns the rules among rules of ptype that are already
+// stored, in the order of rules, using a single query. It runs on the primary
+// database, or on the transaction carried by ctx, so that rules just added are
+// reported.
+//
+// Example:
+//
+//	existing, err := adapter.ExistingPolicies(ctx, "p", rules)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, rule := range existing {
+//	    log.Printf("rule %v already exists", rule)
+//	}
+func (a *Adapter) ExistingPolicies(ctx context.Context, ptype string, rules [][]string) (_ [][]string, err error) {
+	defer wrapOpError("existing_policies", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.ExistingPolicies(ctx, ptype, rules)
+	}
+	defer a.observe("existing_policies")()
 
-	values := existingPolicy.filterValuesWithKey()
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
 
-	return a.delete(ctx, query, values)
-}
+	if len(rules) == 0 {
+		return [][]string{}, nil
+	}
 
-func (a *Adapter) delete(
-	ctx context.Context,
-	query *bun.DeleteQuery,
-	values map[string]string,
-) error {
-	for key, value := range values {
-		query = query.Where(fmt.Sprintf("%s = ?", key), value)
+	tuples := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		tuples = append(tuples, newCasbinPolicy(ptype, rule).values())
 	}
 
-	if _, err := query.Exec(ctx); err != nil {
-		return err
+	var policies []CasbinPolicy
+	if err := a.newSelect(ctx, a.conn(ctx), &policies).
+		Where("? = ?", ptypeColumn, ptype).
+		// Unused columns may hold NULL, see WithInsertIgnoreEmptyTrailing.
+		Where("(COALESCE(?, ''), COALESCE(?, ''), COALESCE(?, ''), "+
+			"COALESCE(?, ''), COALESCE(?, ''), COALESCE(?, '')) IN (?)",
+			valueColumn(0), valueColumn(1), valueColumn(2),
+			valueColumn(3), valueColumn(4), valueColumn(5), bun.In(tuples)).
+		Scan(ctx); err != nil {
+		return nil, err
 	}
 
-	return nil
-}
+	stored := make(map[[7]string]bool, len(policies))
+	for _, policy := range policies {
+		stored[policy.key()] = true
+	}
 
-// RemoveFilteredPolicy removes policy rules that match the filter from the storage.
-// This is part of the Auto-Save feature.
-// This API is explained in the link below:
-// https://casbin.org/docs/management-api/#removefilteredpolicy
-func (a *Adapter) RemoveFilteredPolicy(
-	sec, ptype string,
-	fieldIndex int,
-	fieldValues ...string,
-) error {
-	return a.RemoveFilteredPolicyCtx(context.Background(), sec, ptype, fieldIndex, fieldValues...)
+	existing := make([][]string, 0, len(policies))
+	for _, rule := range rules {
+		if stored[newCasbinPolicy(ptype, rule).key()] {
+			existing = append(existing, rule)
+		}
+	}
+	return existing, nil
 }
 
-// RemoveFilteredPolicyCtx removes policy rules that match the filter from the storage with context.
-// This is part of the Auto-Save feature.
-func (a *Adapter) RemoveFilteredPolicyCtx(
+// selectFilteredPolicy selects on db the policies matched by filteredCondition.
+func (a *Adapter) selectFilteredPolicy(
 	ctx context.Context,
-	sec, ptype string,
+	db bun.IDB,
+	ptype string,
 	fieldIndex int,
-	fieldValues ...string,
-) error {
-	return a.deleteFilteredPolicy(ctx, ptype, fieldIndex, fieldValues...)
+	fieldValues []string,
+) ([]CasbinPolicy, error) {
+	condition, err := a.filteredCondition(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []CasbinPolicy
+	if err := a.newSelect(ctx, db, &policies).
+		ApplyQueryBuilder(condition).
+		Scan(ctx); err != nil {
+		return nil, err
+	}
+	return policies, nil
 }
 
 func (a *Adapter) deleteFilteredPolicy(
@@ -338,36 +1448,105 @@ func (a *Adapter) deleteFilteredPolicy(
 	fieldIndex int,
 	fieldValues ...string,
 ) error {
-	query := a.db.NewDelete().
-		Model((*CasbinPolicy)(nil)).
-		Where("ptype = ?", ptype)
+	condition, err := a.filteredCondition(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return err
+	}
+	if a.deleteBatchSize > 0 {
+		return a.deleteBatches(ctx, condition)
+	}
 
-	for n := 0; n <= 5; n++ {
-		if fieldIndex > n || n >= fieldIndex+len(fieldValues) {
-			continue
-		}
+	if _, err := a.newDelete(ctx, a.conn(ctx)).
+		ApplyQueryBuilder(condition).
+		Exec(ctx); err != nil {
+		return err
+	}
 
-		value := fieldValues[n-fieldIndex]
-		col := fmt.Sprintf("v%d", n)
+	return nil
+}
 
-		if value == "" {
-			query = query.Where(col + " LIKE '%'")
-		} else {
-			query = query.Where(col+" = ?", value)
-		}
+// filteredCondition returns the conditions restricting a query to the rules of
+// ptype whose values match fieldValues from fieldIndex on. An empty field
+// value matches any value, including NULL. Every filtered operation builds its WHERE clause
+// here, so that they all match the same rules.
+func (a *Adapter) filteredCondition(
+	ptype string,
+	fieldIndex int,
+	fieldValues []string,
+) (func(bun.QueryBuilder) bun.QueryBuilder, error) {
+	if err := validateFieldRange(fieldIndex, fieldValues); err != nil {
+		return nil, err
 	}
 
-	if _, err := query.Exec(ctx); err != nil {
-		return err
+	return func(q bun.QueryBuilder) bun.QueryBuilder {
+		q = q.Where("? = ?", ptypeColumn, ptype)
+		for i, value := range fieldValues {
+			// An empty value matches any value, including NULL.
+			if value != "" {
+				q = q.Where(equalCondition(a.caseInsensitive), valueColumn(fieldIndex+i), value)
+			}
+		}
+		return q
+	}, nil
+}
+
+// keyCondition returns the condition restricting a query to the row storing
+// the rule of policy, matching every value column by position.
+func keyCondition(policy CasbinPolicy) func(bun.QueryBuilder) bun.QueryBuilder {
+	return func(q bun.QueryBuilder) bun.QueryBuilder {
+		q = q.Where("? = ?", ptypeColumn, policy.PType)
+		for i, value := range policy.values() {
+			col := valueColumn(i)
+			if value == "" {
+				// Unused columns may hold NULL, see WithInsertIgnoreEmptyTrailing.
+				q = q.Where("? = '' OR ? IS NULL", col, col)
+			} else {
+				q = q.Where("? = ?", col, value)
+			}
+		}
+		return q
 	}
+}
 
+// validateFieldRange reports an error unless the field values starting at
+// fieldIndex all fall within the v0 to v5 columns. Without it, out of range
+// values would be skipped and a filtered delete could match every rule of the
+// ptype.
+func validateFieldRange(fieldIndex int, fieldValues []string) error {
+	if fieldIndex < 0 || fieldIndex > 5 {
+		return fmt.Errorf("%w: field index %d is out of range [0, 5]", ErrInvalidFieldIndex, fieldIndex)
+	}
+	if fieldIndex+len(fieldValues) > 6 {
+		return fmt.Errorf(
+			"%w: %d field values starting at index %d run past v5",
+			ErrInvalidFieldIndex,
+			len(fieldValues),
+			fieldIndex,
+		)
+	}
 	return nil
 }
 
+// checkRules returns an error wrapping ErrEmptyRule if one of rules holds no
+// non-empty value, unless WithAllowEmptyRules is used, or the error of the
+// validator set by WithRowValidator.
+func (a *Adapter) checkRules(ptype string, rules [][]string) error {
+	if !a.allowEmptyRules {
+		for _, rule := range rules {
+			if len(nonEmptyFields(rule)) == 0 {
+				return fmt.Errorf("%w: a rule of %s holds no value", ErrEmptyRule, ptype)
+			}
+		}
+	}
+	return a.validateRules(ptype, rules)
+}
+
 // UpdatePolicy updates a policy rule from storage.
 // This is part of the Auto-Save feature.
 func (a *Adapter) UpdatePolicy(sec, ptype string, oldRule, newRule []string) error {
-	return a.UpdatePolicyCtx(context.Background(), sec, ptype, oldRule, newRule)
+	ctx, cancel := a.operationContext("update_policy")
+	defer cancel()
+	return a.UpdatePolicyCtx(ctx, sec, ptype, oldRule, newRule)
 }
 
 // UpdatePolicyCtx updates a policy rule from storage.
@@ -376,46 +1555,46 @@ func (a *Adapter) UpdatePolicyCtx(
 	ctx context.Context,
 	sec, ptype string,
 	oldRule, newRule []string,
-) error {
+) (err error) {
+	defer wrapOpError("update_policy", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.UpdatePolicyCtx(ctx, sec, ptype, oldRule, newRule)
+	}
+	defer a.observe("update_policy")()
+
+	if err := a.validateRules(ptype, [][]string{newRule}); err != nil {
+		return err
+	}
+	event := PolicyEvent{
+		Op:       EventUpdate,
+		PType:    ptype,
+		Rules:    [][]string{newRule},
+		OldRules: [][]string{oldRule},
+	}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
 	oldPolicy := newCasbinPolicy(ptype, oldRule)
 	newPolicy := newCasbinPolicy(ptype, newRule)
-	return a.updateRecord(ctx, oldPolicy, newPolicy)
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.updateRecord(ctx, oldPolicy, newPolicy)
+	})
+	return a.notify(ctx, err, event)
 }
 
 func (a *Adapter) updateRecord(ctx context.Context, oldPolicy, newPolicy CasbinPolicy) error {
-	query := a.db.NewUpdate().
-		Model(&newPolicy).
-		Where("ptype = ?", oldPolicy.PType)
-
-	values := oldPolicy.filterValuesWithKey()
-
-	return a.update(ctx, query, values)
-}
-
-func (a *Adapter) updateRecordInTx(
-	ctx context.Context,
-	tx bun.Tx,
-	oldPolicy, newPolicy CasbinPolicy,
-) error {
-	query := tx.NewUpdate().
-		Model(&newPolicy).
-		Where("ptype = ?", oldPolicy.PType)
-
-	values := oldPolicy.filterValuesWithKey()
-
-	return a.update(ctx, query, values)
+	return a.update(ctx, a.newRuleUpdate(ctx, a.conn(ctx), newPolicy), oldPolicy)
 }
 
+// update runs query, updating the row storing oldPolicy. The rule is matched
+// positionally like in delete.
 func (a *Adapter) update(
 	ctx context.Context,
 	query *bun.UpdateQuery,
-	values map[string]string,
+	oldPolicy CasbinPolicy,
 ) error {
-	for key, value := range values {
-		query = query.Where(fmt.Sprintf("%s = ?", key), value)
-	}
-
-	if _, err := query.Exec(ctx); err != nil {
+	if _, err := query.ApplyQueryBuilder(keyCondition(oldPolicy)).Exec(ctx); err != nil {
 		return err
 	}
 
@@ -424,7 +1603,9 @@ func (a *Adapter) update(
 
 // UpdatePolicies updates some policy rules to storage, like db, redis.
 func (a *Adapter) UpdatePolicies(sec, ptype string, oldRules, newRules [][]string) error {
-	return a.UpdatePoliciesCtx(context.Background(), sec, ptype, oldRules, newRules)
+	ctx, cancel := a.operationContext("update_policies")
+	defer cancel()
+	return a.UpdatePoliciesCtx(ctx, sec, ptype, oldRules, newRules)
 }
 
 // UpdatePoliciesCtx updates some policy rules to storage, like db, redis.
@@ -432,7 +1613,29 @@ func (a *Adapter) UpdatePoliciesCtx(
 	ctx context.Context,
 	sec, ptype string,
 	oldRules, newRules [][]string,
-) error {
+) (err error) {
+	defer wrapOpError("update_policies", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.UpdatePoliciesCtx(ctx, sec, ptype, oldRules, newRules)
+	}
+	defer a.observe("update_policies")()
+
+	if len(oldRules) != len(newRules) {
+		return fmt.Errorf("casbun: %d old rules for %d new rules", len(oldRules), len(newRules))
+	}
+	if err := a.validateRules(ptype, newRules); err != nil {
+		return err
+	}
+	event := PolicyEvent{
+		Op:       EventUpdate,
+		PType:    ptype,
+		Rules:    newRules,
+		OldRules: oldRules,
+	}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
 	oldPolicies := make([]CasbinPolicy, 0, len(oldRules))
 	newPolicies := make([]CasbinPolicy, 0, len(newRules))
 	for _, rule := range oldRules {
@@ -442,18 +1645,110 @@ func (a *Adapter) UpdatePoliciesCtx(
 		newPolicies = append(newPolicies, newCasbinPolicy(ptype, rule))
 	}
 
-	return a.db.RunInTx(
-		ctx,
-		&sql.TxOptions{},
-		func(ctx context.Context, tx bun.Tx) error {
-			for i := range oldPolicies {
-				if err := a.updateRecordInTx(ctx, tx, oldPolicies[i], newPolicies[i]); err != nil {
-					return err
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				if a.updateStrategy == UpdateDeleteInsert {
+					return a.replacePolicyRecords(ctx, tx, oldPolicies, newPolicies)
 				}
-			}
-			return nil
-		},
-	)
+				if order, chained := updateOrder(oldPolicies, newPolicies); chained {
+					// A cycle can not be rewritten in place, so its rules are
+					// replaced instead.
+					if order == nil {
+						return a.replacePolicyRecords(ctx, tx, oldPolicies, newPolicies)
+					}
+					return a.updateRecordsInOrder(ctx, tx, oldPolicies, newPolicies, order)
+				}
+				for start := 0; start < len(oldPolicies); start += updateBatchSize {
+					end := min(start+updateBatchSize, len(oldPolicies))
+					if err := a.updateRecordsInTx(
+						ctx,
+						tx,
+						oldPolicies[start:end],
+						newPolicies[start:end],
+					); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		)
+	})
+	return a.notify(ctx, err, event)
+}
+
+// updateRecordsInTx updates a batch of policies with a single statement.
+// Every value column is assigned through a CASE expression keyed on the old
+// rule, so each row is rewritten to the new rule it was matched against. The
+// mapping must not be chained, see updateOrder.
+func (a *Adapter) updateRecordsInTx(
+	ctx context.Context,
+	tx bun.Tx,
+	oldPolicies, newPolicies []CasbinPolicy,
+) error {
+	conditions := make([]string, 0, len(oldPolicies))
+	conditionArgs := make([][]interface{}, 0, len(oldPolicies))
+	for _, policy := range oldPolicies {
+		condition, args := matchCondition(policy)
+		conditions = append(conditions, condition)
+		conditionArgs = append(conditionArgs, args)
+	}
+
+	query := a.newUpdate(ctx, tx, (*CasbinPolicy)(nil)).
+		Where("? = ?", ptypeColumn, oldPolicies[0].PType)
+
+	for n := 0; n <= 5; n++ {
+		col := valueColumn(n)
+
+		var b strings.Builder
+		args := []interface{}{col}
+		b.WriteString("? = CASE")
+		for i := range conditions {
+			b.WriteString(" WHEN " + conditions[i] + " THEN ?")
+			args = append(args, conditionArgs[i]...)
+			args = append(args, newPolicies[i].values()[n])
+		}
+		b.WriteString(" ELSE ? END")
+		args = append(args, col)
+
+		query = query.Set(b.String(), args...)
+	}
+	query = a.incrementVersion(query)
+
+	query = query.WhereGroup(" AND ", func(q *bun.UpdateQuery) *bun.UpdateQuery {
+		for i := range conditions {
+			q = q.WhereOr(conditions[i], conditionArgs[i]...)
+		}
+		return q
+	})
+
+	if _, err := query.Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// matchCondition returns the WHERE fragment matching the values of the
+// policy positionally, mirroring the matching done by update and delete.
+func matchCondition(policy CasbinPolicy) (string, []interface{}) {
+	parts := make([]string, 0, 6)
+	args := make([]interface{}, 0, 12)
+	for i, v := range policy.values() {
+		col := valueColumn(i)
+		if v == "" {
+			// Unused columns may hold NULL, see WithInsertIgnoreEmptyTrailing.
+			parts = append(parts, "(? = '' OR ? IS NULL)")
+			args = append(args, col, col)
+			continue
+		}
+		parts = append(parts, "? = ?")
+		args = append(args, col, v)
+	}
+
+	return "(" + strings.Join(parts, " AND ") + ")", args
 }
 
 // UpdateFilteredPolicies deletes old rules and adds new rules.
@@ -463,8 +1758,10 @@ func (a *Adapter) UpdateFilteredPolicies(
 	fieldIndex int,
 	fieldValues ...string,
 ) ([][]string, error) {
+	ctx, cancel := a.operationContext("update_filtered_policies")
+	defer cancel()
 	return a.UpdateFilteredPoliciesCtx(
-		context.Background(),
+		ctx,
 		sec,
 		ptype,
 		newRules,
@@ -473,67 +1770,94 @@ func (a *Adapter) UpdateFilteredPolicies(
 }
 
 // UpdateFilteredPoliciesCtx deletes old rules and adds new rules.
+// The old rules are locked with SELECT ... FOR UPDATE where the dialect
+// supports it, so the returned rules are exactly the ones deleted.
 func (a *Adapter) UpdateFilteredPoliciesCtx(
 	ctx context.Context,
 	sec, ptype string,
 	newRules [][]string,
 	fieldIndex int,
 	fieldValues ...string,
-) ([][]string, error) {
-	newPolicies := make([]CasbinPolicy, 0, len(newRules))
-	for _, rule := range newRules {
-		newPolicies = append(newPolicies, newCasbinPolicy(ptype, rule))
+) (_ [][]string, err error) {
+	defer wrapOpError("update_filtered_policies", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.UpdateFilteredPoliciesCtx(ctx, sec, ptype, newRules, fieldIndex, fieldValues...)
 	}
+	defer a.observe("update_filtered_policies")()
 
-	tx, err := a.db.BeginTx(ctx, &sql.TxOptions{})
+	condition, err := a.filteredCondition(ptype, fieldIndex, fieldValues)
 	if err != nil {
 		return nil, err
 	}
+	if err := a.validateRules(ptype, newRules); err != nil {
+		return nil, err
+	}
+	event := PolicyEvent{Op: EventUpdate, PType: ptype, Rules: newRules}
+	if err := a.before(ctx, event); err != nil {
+		return nil, err
+	}
 
-	oldPolicies := make([]CasbinPolicy, 0)
-	selectQuery := tx.NewSelect().
-		Model(&oldPolicies).
-		Where("ptype = ?", ptype)
-	deleteQuery := tx.NewDelete().
-		Model((*CasbinPolicy)(nil)).
-		Where("ptype = ?", ptype)
-
-	for n := 0; n <= 5; n++ {
-		if fieldIndex > n || n >= fieldIndex+len(fieldValues) {
-			continue
-		}
-
-		value := fieldValues[n-fieldIndex]
-		col := fmt.Sprintf("v%d", n)
-		condition := col + " LIKE '%'"
-		if value != "" {
-			condition = col + " = ?"
-		}
-
-		selectQuery = selectQuery.Where(condition, value)
-		deleteQuery = deleteQuery.Where(condition, value)
+	newPolicies := make([]CasbinPolicy, 0, len(newRules))
+	for _, rule := range newRules {
+		newPolicies = append(newPolicies, newCasbinPolicy(ptype, rule))
 	}
 
-	if err := selectQuery.Scan(ctx); err != nil {
-		if err := tx.Rollback(); err != nil {
-			return nil, err
-		}
+	var out [][]string
+	err = a.retry(ctx, func(ctx context.Context) error {
+		out, err = a.updateFilteredPolicies(ctx, condition, newPolicies)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	if _, err := deleteQuery.Exec(ctx); err != nil {
-		if err := tx.Rollback(); err != nil {
-			return nil, err
-		}
-		return nil, err
+	for _, rule := range out {
+		// The returned rules start with their ptype.
+		event.OldRules = append(event.OldRules, rule[1:])
 	}
+	return out, a.notify(ctx, nil, event)
+}
 
-	if _, err := tx.NewInsert().
-		Model(&newPolicies).
-		Exec(ctx); err != nil {
-		if err := tx.Rollback(); err != nil {
-			return nil, err
-		}
+func (a *Adapter) updateFilteredPolicies(
+	ctx context.Context,
+	condition func(bun.QueryBuilder) bun.QueryBuilder,
+	newPolicies []CasbinPolicy,
+) ([][]string, error) {
+	var oldPolicies []CasbinPolicy
+	err := a.conn(ctx).RunInTx(
+		ctx,
+		a.txOptions(),
+		func(ctx context.Context, tx bun.Tx) error {
+			oldPolicies = make([]CasbinPolicy, 0)
+			query := a.newSelect(ctx, tx, &oldPolicies).
+				ApplyQueryBuilder(condition)
+			// Lock the matched rows, so that the returned rules are the ones
+			// deleted below even if a concurrent writer changes them.
+			// SQLite locks the whole database on write instead.
+			if a.dialect().selectForUpdate {
+				query = query.For("UPDATE")
+			}
+			if err := query.Scan(ctx); err != nil {
+				return err
+			}
+
+			if _, err := a.newDelete(ctx, tx).
+				ApplyQueryBuilder(condition).
+				Exec(ctx); err != nil {
+				return err
+			}
+
+			// Without new rules the update is a plain filtered delete.
+			if len(newPolicies) > 0 {
+				if _, err := a.newInsert(ctx, tx, &newPolicies).
+					Exec(ctx); err != nil {
+					return insertError(err)
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
 		return nil, err
 	}
 
@@ -541,6 +1865,5 @@ func (a *Adapter) UpdateFilteredPoliciesCtx(
 	for _, policy := range oldPolicies {
 		out = append(out, policy.toSlice())
 	}
-
-	return out, tx.Commit()
+	return out, nil
 }
@@ -3,7 +3,15 @@ package casbun_test
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
@@ -40,6 +48,25 @@ func initDB() *bun.DB {
 	return db
 }
 
+// initFileDB opens a file backed database in WAL mode, which unlike the
+// in-memory one is shared by every connection of the pool and lets readers
+// run concurrently with a writer.
+func initFileDB(t *testing.T) *bun.DB {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "casbin.db") +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	sqldb, err := sql.Open(sqliteshim.ShimName, dsn)
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db
+}
+
 func ensureHasPolicy(t *testing.T, db *bun.DB, e *casbin.Enforcer, want [][]string) {
 	t.Helper()
 
@@ -151,6 +178,125 @@ func TestSavePolicy(t *testing.T) {
 	ensureHasPolicy(t, db, e, [][]string{{"alice", "data", "write"}})
 }
 
+var multiSectionModelStr = `
+    [request_definition]
+    r = sub, obj, act
+    r2 = sub, obj
+
+    [policy_definition]
+    p = sub, obj, act
+    p2 = sub, obj
+
+    [role_definition]
+    g = _, _
+    g2 = _, _
+
+    [policy_effect]
+    e = some(where (p.eft == allow))
+    e2 = some(where (p.eft == allow))
+
+    [matchers]
+    m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+    m2 = g2(r2.sub, p2.sub) && r2.obj == p2.obj
+`
+
+func TestSavePolicyAllSections(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := casbun.NewAdapter(context.Background(), initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(multiSectionModelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	e.EnableAutoSave(false)
+
+	if _, err := e.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if _, err := e.AddNamedPolicy("p2", "bob", "data2"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if _, err := e.AddGroupingPolicy("alice", "admin"); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	if _, err := e.AddNamedGroupingPolicy("g2", "bob", "reader"); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+
+	reloaded, _ := model.NewModelFromString(multiSectionModelStr)
+	if err := adapter.LoadPolicy(reloaded); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	tests := []struct {
+		sec, ptype string
+		want       [][]string
+	}{
+		{sec: "p", ptype: "p", want: [][]string{{"alice", "data1", "read"}}},
+		{sec: "p", ptype: "p2", want: [][]string{{"bob", "data2"}}},
+		{sec: "g", ptype: "g", want: [][]string{{"alice", "admin"}}},
+		{sec: "g", ptype: "g2", want: [][]string{{"bob", "reader"}}},
+	}
+	for _, tt := range tests {
+		got, err := reloaded.GetPolicy(tt.sec, tt.ptype)
+		if err != nil {
+			t.Fatalf("unable to get %s policy: %v", tt.ptype, err)
+		}
+		if !util.Array2DEquals(tt.want, got) {
+			t.Errorf("got %s policy %v, want %v", tt.ptype, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPolicySections(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := casbun.NewAdapter(context.Background(), initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicy("p", "p2", []string{"bob", "data2"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.AddPolicy("p", "x0", []string{"carol", "data3"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(multiSectionModelStr)
+	// A ptype whose name does not start with its section.
+	m.AddDef("p", "x0", "sub, obj")
+
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	tests := []struct {
+		ptype string
+		want  [][]string
+	}{
+		{ptype: "p2", want: [][]string{{"bob", "data2"}}},
+		{ptype: "x0", want: [][]string{{"carol", "data3"}}},
+	}
+	for _, tt := range tests {
+		got, err := m.GetPolicy("p", tt.ptype)
+		if err != nil {
+			t.Fatalf("unable to get %s policy: %v", tt.ptype, err)
+		}
+		if !util.Array2DEquals(tt.want, got) {
+			t.Errorf("got %s policy %v, want %v", tt.ptype, got, tt.want)
+		}
+	}
+}
+
 func TestAddPolicy(t *testing.T) {
 	t.Parallel()
 
@@ -201,6 +347,76 @@ func TestAddPolicies(t *testing.T) {
 	ensureHasPolicy(t, db, e, policies)
 }
 
+func TestAddPoliciesAtomic(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	// The rules span several insert batches, the last of which repeats a rule
+	// of the first.
+	rules := make([][]string, 0, 600)
+	for i := 0; i < 599; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+	}
+	rules = append(rules, rules[0])
+
+	err = adapter.AddPoliciesCtx(ctx, "p", "p", rules)
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Fatalf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+
+	count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("got %d rules from the failed batches, want 0", count)
+	}
+}
+
+func TestAddPoliciesIgnoreExisting(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	added, err := adapter.AddPoliciesIgnoreExisting(ctx, "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+		{"carol", "data2", "write"},
+	})
+	if err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if added != 2 {
+		t.Errorf("got %d added rules, want 2", added)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+		{"carol", "data2", "write"},
+	})
+}
+
 func TestRemovePolicy(t *testing.T) {
 	t.Parallel()
 
@@ -304,118 +520,1162 @@ func TestRemoveFilteredPolicy(t *testing.T) {
 	})
 }
 
-func TestUpdatePolicy(t *testing.T) {
+func TestRemoveFilteredPolicyReturning(t *testing.T) {
 	t.Parallel()
 
+	ctx := context.Background()
 	db := initDB()
-	adapter, err := casbun.NewAdapter(context.Background(), db)
+	adapter, err := casbun.NewAdapter(ctx, db)
 	if err != nil {
 		t.Fatalf("unable to create adapter: %v", err)
 	}
 
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+		{"alice", "data2", "write"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	removed, err := adapter.RemoveFilteredPolicyReturning(ctx, "p", 0, "alice")
+	if err != nil {
+		t.Fatalf("unable to remove filtered policy: %v", err)
+	}
+	want := [][]string{
+		{"p", "alice", "data1", "read"},
+		{"p", "alice", "data2", "write"},
+	}
+	if !util.SortedArray2DEquals(want, removed) {
+		t.Errorf("got removed rules %v, want %v", removed, want)
+	}
+
 	m, _ := model.NewModelFromString(modelStr)
 	e, err := casbin.NewEnforcer(m, adapter)
 	if err != nil {
 		t.Fatalf("failed to create enforcer: %v", err)
 	}
+	ensureHasPolicy(t, db, e, [][]string{{"bob", "data1", "read"}})
+}
 
-	policies := [][]string{
-		{"alice", "data1", "write"},
-		{"bob", "data1", "read"},
-		{"alice", "data2", "write"},
+func TestRemoveAllByPType(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
 	}
 
-	if _, err := e.AddPolicies(policies); err != nil {
+	policies := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", policies); err != nil {
 		t.Fatalf("failed to add policies: %v", err)
 	}
-	ensureHasPolicy(t, db, e, policies)
+	if err := adapter.AddPoliciesCtx(ctx, "g", "g", [][]string{
+		{"alice", "admin"},
+		{"bob", "admin"},
+		{"carol", "reader"},
+	}); err != nil {
+		t.Fatalf("failed to add grouping policies: %v", err)
+	}
 
-	newPolicy := []string{"alice", "data1", "read"}
-	ok, err := e.UpdatePolicy(policies[0], newPolicy)
-	if !ok || err != nil {
-		t.Fatalf("unable to update policy: %v", err)
+	removed, err := adapter.RemoveAllByPType(ctx, "g")
+	if err != nil {
+		t.Fatalf("unable to remove grouping policies: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("got %d removed rules, want 3", removed)
 	}
 
-	policies[0] = newPolicy
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
 	ensureHasPolicy(t, db, e, policies)
+
+	grouping, err := e.GetGroupingPolicy()
+	if err != nil {
+		t.Fatalf("unable to get grouping policy: %v", err)
+	}
+	if len(grouping) != 0 {
+		t.Errorf("got grouping policy %v, want none", grouping)
+	}
 }
 
-func TestUpdatePolicies(t *testing.T) {
+func TestPreviewRemoveFilteredPolicy(t *testing.T) {
 	t.Parallel()
 
+	ctx := context.Background()
 	db := initDB()
-	adapter, err := casbun.NewAdapter(context.Background(), db)
+	adapter, err := casbun.NewAdapter(ctx, db)
 	if err != nil {
 		t.Fatalf("unable to create adapter: %v", err)
 	}
 
-	m, _ := model.NewModelFromString(modelStr)
-	e, err := casbin.NewEnforcer(m, adapter)
-	if err != nil {
-		t.Fatalf("failed to create enforcer: %v", err)
-	}
-
-	policies := [][]string{
-		{"alice", "data1", "write"},
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
 		{"bob", "data1", "read"},
 		{"alice", "data2", "write"},
+		{"carol", "data1", "write"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
 	}
 
-	if _, err := e.AddPolicies(policies); err != nil {
-		t.Fatalf("failed to add policies: %v", err)
+	preview, err := adapter.PreviewRemoveFilteredPolicy(ctx, "p", 1, "data1", "")
+	if err != nil {
+		t.Fatalf("unable to preview removal: %v", err)
 	}
-	ensureHasPolicy(t, db, e, policies)
 
-	newPolicies := [][]string{
+	want := [][]string{
 		{"alice", "data1", "read"},
-		{"alice", "data2", "read"},
+		{"bob", "data1", "read"},
+		{"carol", "data1", "write"},
+	}
+	if !util.SortedArray2DEquals(want, preview) {
+		t.Errorf("got preview %v, want %v", preview, want)
 	}
 
-	old := [][]string{policies[0], policies[2]}
-	ok, err := e.UpdatePolicies(old, newPolicies)
-	if !ok || err != nil {
-		t.Fatalf("unable to update policy: %v", err)
+	if err := adapter.RemoveFilteredPolicyCtx(ctx, "p", "p", 1, "data1", ""); err != nil {
+		t.Fatalf("unable to remove filtered policy: %v", err)
 	}
 
-	ensureHasPolicy(t, db, e, [][]string{newPolicies[0], policies[1], newPolicies[1]})
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	remaining, _ := m.GetPolicy("p", "p")
+	if !util.SortedArray2DEquals([][]string{{"alice", "data2", "write"}}, remaining) {
+		t.Errorf("removal did not match the preview, remaining %v", remaining)
+	}
+
+	if _, err := adapter.PreviewRemoveFilteredPolicy(ctx, "p", 7, "data1"); !errors.Is(err, casbun.ErrInvalidFieldIndex) {
+		t.Errorf("got %v, want %v", err, casbun.ErrInvalidFieldIndex)
+	}
 }
 
-func TestUpdateFilteredPolicies(t *testing.T) {
+func TestGetFilteredPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
 	db := initDB()
-	adapter, err := casbun.NewAdapter(context.Background(), db)
+	adapter, err := casbun.NewAdapter(ctx, db)
 	if err != nil {
 		t.Fatalf("unable to create adapter: %v", err)
 	}
 
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "write"},
+		{"alice", "data2", "write"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	got, err := adapter.GetFilteredPolicy(ctx, "p", 1, "data1")
+	if err != nil {
+		t.Fatalf("unable to get filtered policy: %v", err)
+	}
+
+	want := [][]string{
+		{"p", "alice", "data1", "read"},
+		{"p", "bob", "data1", "write"},
+	}
+	if !util.SortedArray2DEquals(want, got) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := adapter.RemoveFilteredPolicyCtx(ctx, "p", "p", 1, "data1"); err != nil {
+		t.Fatalf("unable to remove filtered policy: %v", err)
+	}
+
 	m, _ := model.NewModelFromString(modelStr)
-	e, err := casbin.NewEnforcer(m, adapter)
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	remaining, _ := m.GetPolicy("p", "p")
+	if !util.SortedArray2DEquals([][]string{{"alice", "data2", "write"}}, remaining) {
+		t.Errorf("removal did not match the filtered rules, remaining %v", remaining)
+	}
+}
+
+func TestRemovePolicyExists(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
 	if err != nil {
-		t.Fatalf("failed to create enforcer: %v", err)
+		t.Fatalf("unable to create adapter: %v", err)
 	}
 
-	policies := [][]string{
-		{"alice", "data1", "read"},
-		{"bob", "data1", "read"},
-		{"alice", "data2", "write"},
+	rule := []string{"alice", "data1", "read"}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
 	}
 
-	if _, err := e.AddPolicies(policies); err != nil {
-		t.Fatalf("failed to add policies: %v", err)
+	removed, err := adapter.RemovePolicyExists(ctx, "p", rule)
+	if err != nil {
+		t.Fatalf("failed to remove policy: %v", err)
+	}
+	if !removed {
+		t.Errorf("got removed false for a stored rule, want true")
 	}
 
-	ok, err := e.UpdateFilteredPolicies(
-		[][]string{{"alice", "data1", "write"}, {"bob", "data1", "write"}},
-		1,
-		"data1",
-		"read",
-	)
-	if err != nil || !ok {
-		t.Fatalf("unable to update filtered policies: %v", err)
+	removed, err = adapter.RemovePolicyExists(ctx, "p", rule)
+	if err != nil {
+		t.Fatalf("failed to remove absent policy: %v", err)
 	}
+	if removed {
+		t.Errorf("got removed true for an absent rule, want false")
+	}
+}
 
-	ensureHasPolicy(t, db, e, [][]string{
-		{"alice", "data2", "write"},
-		{"alice", "data1", "write"},
-		{"bob", "data1", "write"},
-	})
+func TestEmptyRule(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{}); !errors.Is(err, casbun.ErrEmptyRule) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrEmptyRule)
+	}
+	rules := [][]string{{"alice", "data1", "read"}, {"", "", ""}}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); !errors.Is(err, casbun.ErrEmptyRule) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrEmptyRule)
+	}
+	count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("got %d rules after the rejected adds, want 0", count)
+	}
+
+	allowing, err := casbun.NewAdapter(ctx, initDB(), casbun.WithAllowEmptyRules())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := allowing.AddPolicyCtx(ctx, "p", "p", []string{}); err != nil {
+		t.Errorf("failed to add an allowed empty rule: %v", err)
+	}
+}
+
+func TestLoadPolicyArray(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	got, err := adapter.LoadPolicyArray(ctx)
+	if err != nil {
+		t.Fatalf("unable to load policy array: %v", err)
+	}
+	want := map[string][][]string{
+		"p": {{"alice", "data1", "read"}, {"bob", "data2", "write"}},
+		"g": {{"alice", "admin"}},
+	}
+	if len(got) != len(want) {
+		t.Errorf("got ptypes %v, want %v", got, want)
+	}
+	for ptype, rules := range want {
+		if !util.Array2DEquals(rules, got[ptype]) {
+			t.Errorf("got %s rules %v, want %v", ptype, got[ptype], rules)
+		}
+	}
+}
+
+func TestGetPoliciesPage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// ScanAndCount runs its queries concurrently, and every connection to
+	// the in-memory database opens an empty one.
+	db := initDB()
+	db.SetMaxOpenConns(1)
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rules := make([][]string, 0, 25)
+	for i := 0; i < 25; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"user1", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for offset := 0; offset < 30; offset += 10 {
+		page, total, err := adapter.GetPoliciesPage(ctx, "p", offset, 10)
+		if err != nil {
+			t.Fatalf("unable to get page at %d: %v", offset, err)
+		}
+		if total != 25 {
+			t.Errorf("got total %d, want 25", total)
+		}
+		if want := min(10, 25-offset); len(page) != want {
+			t.Errorf("got %d rules at offset %d, want %d", len(page), offset, want)
+		}
+		for _, rule := range page {
+			if seen[rule[1]] {
+				t.Errorf("rule %v returned twice", rule)
+			}
+			seen[rule[1]] = true
+		}
+	}
+	if len(seen) != 25 {
+		t.Errorf("got %d distinct rules, want 25", len(seen))
+	}
+
+	if _, _, err := adapter.GetPoliciesPage(ctx, "p", 0, 0); err == nil {
+		t.Errorf("expected an error for an empty page")
+	}
+}
+
+func TestExistingPolicies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"carol", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	got, err := adapter.ExistingPolicies(ctx, "p", [][]string{
+		{"bob", "data2", "write"},
+		{"alice", "data1", "write"},
+		{"carol", "data1", "read"},
+		{"alice", "data1", "read"},
+		{"alice", "data1"},
+	})
+	if err != nil {
+		t.Fatalf("unable to check existing policies: %v", err)
+	}
+	want := [][]string{
+		{"bob", "data2", "write"},
+		{"alice", "data1", "read"},
+	}
+	if !util.Array2DEquals(want, got) {
+		t.Errorf("got existing policies %v, want %v", got, want)
+	}
+}
+
+func TestRemoveFilteredPolicyOutOfRange(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		fieldIndex  int
+		fieldValues []string
+	}{
+		{name: "index past v5", fieldIndex: 7, fieldValues: []string{"alice"}},
+		{name: "negative index", fieldIndex: -1, fieldValues: []string{"alice"}},
+		{name: "values past v5", fieldIndex: 4, fieldValues: []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := adapter.RemoveFilteredPolicy("p", "p", tt.fieldIndex, tt.fieldValues...)
+			if !errors.Is(err, casbun.ErrInvalidFieldIndex) {
+				t.Errorf("got %v, want %v", err, casbun.ErrInvalidFieldIndex)
+			}
+		})
+	}
+
+	count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(context.Background())
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("got %d policies, want 2", count)
+	}
+}
+
+func TestUpdatePolicy(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+		{"alice", "data2", "write"},
+	}
+
+	if _, err := e.AddPolicies(policies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	ensureHasPolicy(t, db, e, policies)
+
+	newPolicy := []string{"alice", "data1", "read"}
+	ok, err := e.UpdatePolicy(policies[0], newPolicy)
+	if !ok || err != nil {
+		t.Fatalf("unable to update policy: %v", err)
+	}
+
+	policies[0] = newPolicy
+	ensureHasPolicy(t, db, e, policies)
+}
+
+func TestUpdatePolicies(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+		{"alice", "data2", "write"},
+	}
+
+	if _, err := e.AddPolicies(policies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	ensureHasPolicy(t, db, e, policies)
+
+	newPolicies := [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "read"},
+	}
+
+	old := [][]string{policies[0], policies[2]}
+	ok, err := e.UpdatePolicies(old, newPolicies)
+	if !ok || err != nil {
+		t.Fatalf("unable to update policy: %v", err)
+	}
+
+	ensureHasPolicy(t, db, e, [][]string{newPolicies[0], policies[1], newPolicies[1]})
+}
+
+func TestUpdateFilteredPolicies(t *testing.T) {
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+		{"alice", "data2", "write"},
+	}
+
+	if _, err := e.AddPolicies(policies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	ok, err := e.UpdateFilteredPolicies(
+		[][]string{{"alice", "data1", "write"}, {"bob", "data1", "write"}},
+		1,
+		"data1",
+		"read",
+	)
+	if err != nil || !ok {
+		t.Fatalf("unable to update filtered policies: %v", err)
+	}
+
+	ensureHasPolicy(t, db, e, [][]string{
+		{"alice", "data2", "write"},
+		{"alice", "data1", "write"},
+		{"bob", "data1", "write"},
+	})
+}
+
+func TestUpdateFilteredPoliciesRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", policies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	// The new rule collides with a rule left out of the filter, which fails
+	// the insert after the matched rules were deleted.
+	_, err = adapter.UpdateFilteredPoliciesCtx(ctx, "p", "p", [][]string{{"bob", "data2", "write"}}, 0, "alice")
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Fatalf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+
+	if inUse := db.DB.Stats().InUse; inUse != 0 {
+		t.Errorf("got %d connections in use, want 0", inUse)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	ensureHasPolicy(t, db, e, policies)
+}
+
+func TestPositionalMatch(t *testing.T) {
+	t.Parallel()
+
+	// The rules share a prefix and differ only in a later field left empty by
+	// one of them, which must not match the other.
+	stored := [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data1", "read", "deny"},
+		{"bob", "", "read"},
+		{"bob", "data1", "read"},
+	}
+
+	tests := []struct {
+		name string
+		op   func(ctx context.Context, adapter *casbun.Adapter) error
+		want [][]string
+	}{
+		{
+			name: "remove",
+			op: func(ctx context.Context, adapter *casbun.Adapter) error {
+				return adapter.RemovePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+			},
+			want: [][]string{stored[1], stored[2], stored[3]},
+		},
+		{
+			name: "remove empty middle field",
+			op: func(ctx context.Context, adapter *casbun.Adapter) error {
+				return adapter.RemovePoliciesCtx(ctx, "p", "p", [][]string{{"bob", "", "read"}})
+			},
+			want: [][]string{stored[0], stored[1], stored[3]},
+		},
+		{
+			name: "update",
+			op: func(ctx context.Context, adapter *casbun.Adapter) error {
+				return adapter.UpdatePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1", "write"})
+			},
+			want: [][]string{{"alice", "data1", "write"}, stored[1], stored[2], stored[3]},
+		},
+		{
+			name: "update empty middle field",
+			op: func(ctx context.Context, adapter *casbun.Adapter) error {
+				return adapter.UpdatePoliciesCtx(ctx, "p", "p", [][]string{{"bob", "", "read"}}, [][]string{{"bob", "", "write"}})
+			},
+			want: [][]string{stored[0], stored[1], {"bob", "", "write"}, stored[3]},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			adapter, err := casbun.NewAdapter(ctx, initDB())
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+			if err := adapter.AddPoliciesCtx(ctx, "p", "p", stored); err != nil {
+				t.Fatalf("failed to add policies: %v", err)
+			}
+
+			if err := tt.op(ctx, adapter); err != nil {
+				t.Fatalf("failed to run %s: %v", tt.name, err)
+			}
+
+			policies, err := adapter.SelectPolicies(ctx, casbun.Filter{})
+			if err != nil {
+				t.Fatalf("failed to select policies: %v", err)
+			}
+			// The rules are compared on all six fields, as loads drop the empty
+			// ones.
+			got := make([]string, 0, len(policies))
+			for _, policy := range policies {
+				got = append(got, strings.Join([]string{policy.V0, policy.V1, policy.V2, policy.V3, policy.V4, policy.V5}, ","))
+			}
+			want := make([]string, 0, len(tt.want))
+			for _, rule := range tt.want {
+				want = append(want, strings.Join(append(rule, make([]string, 6-len(rule))...), ","))
+			}
+			sort.Strings(got)
+			sort.Strings(want)
+			if !slices.Equal(got, want) {
+				t.Errorf("got rules %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestUpdatePoliciesBatch(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	const n = 300
+	oldPolicies := make([][]string, 0, n)
+	newPolicies := make([][]string, 0, n)
+	for i := 0; i < n; i++ {
+		oldPolicies = append(oldPolicies, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+		newPolicies = append(newPolicies, []string{fmt.Sprintf("user%d", i), "data2", "write"})
+	}
+
+	if _, err := e.AddPolicies(oldPolicies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	if ok, err := e.UpdatePolicies(oldPolicies, newPolicies); !ok || err != nil {
+		t.Fatalf("unable to update policies: %v", err)
+	}
+
+	ensureHasPolicy(t, db, e, newPolicies)
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	ensureHasPolicy(t, db, e, newPolicies)
+}
+
+func BenchmarkUpdatePolicies(b *testing.B) {
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		b.Fatalf("unable to create adapter: %v", err)
+	}
+
+	const n = 300
+	rules := [2][][]string{}
+	for i := 0; i < n; i++ {
+		rules[0] = append(rules[0], []string{fmt.Sprintf("user%d", i), "data1", "read"})
+		rules[1] = append(rules[1], []string{fmt.Sprintf("user%d", i), "data2", "write"})
+	}
+
+	if err := adapter.AddPolicies("p", "p", rules[0]); err != nil {
+		b.Fatalf("failed to add policies: %v", err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		from, to := rules[i%2], rules[(i+1)%2]
+		if err := adapter.UpdatePolicies("p", "p", from, to); err != nil {
+			b.Fatalf("unable to update policies: %v", err)
+		}
+	}
+}
+
+func TestReadDB(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary := initDB()
+	replica := initDB()
+
+	// The replica gets its own copy of the table, holding a rule that only
+	// exists there.
+	if _, err := replica.NewCreateTable().
+		Model((*casbun.CasbinPolicy)(nil)).
+		Exec(ctx); err != nil {
+		t.Fatalf("unable to create replica table: %v", err)
+	}
+	if _, err := replica.NewInsert().
+		Model(&casbun.CasbinPolicy{PType: "p", V0: "bob", V1: "data1", V2: "read"}).
+		Exec(ctx); err != nil {
+		t.Fatalf("unable to insert into replica: %v", err)
+	}
+
+	adapter, err := casbun.NewAdapter(ctx, primary, casbun.WithReadDB(replica))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	got, _ := m.GetPolicy("p", "p")
+	want := [][]string{{"bob", "data1", "read"}}
+	if !util.Array2DEquals(want, got) {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+
+	for name, db := range map[string]*bun.DB{"primary": primary, "replica": replica} {
+		count, err := db.NewSelect().
+			Model((*casbun.CasbinPolicy)(nil)).
+			Where("v0 = ?", "alice").
+			Count(ctx)
+		if err != nil {
+			t.Fatalf("unable to count %s policies: %v", name, err)
+		}
+
+		want := 0
+		if name == "primary" {
+			want = 1
+		}
+		if count != want {
+			t.Errorf("got %d inserted rows on %s, want %d", count, name, want)
+		}
+	}
+}
+
+func TestClear(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		strategy casbun.SaveStrategy
+	}{
+		{name: "truncate", strategy: casbun.SaveTruncate},
+		{name: "upsert", strategy: casbun.SaveUpsert},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := initDB()
+			adapter, err := casbun.NewAdapter(
+				context.Background(),
+				db,
+				casbun.WithSaveStrategy(tt.strategy),
+			)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			if err := adapter.AddPolicies("p", "p", [][]string{
+				{"alice", "data1", "read"},
+				{"bob", "data1", "write"},
+			}); err != nil {
+				t.Fatalf("failed to add policies: %v", err)
+			}
+			if err := adapter.AddPolicy("g", "g", []string{"bob", "admin"}); err != nil {
+				t.Fatalf("failed to add policy: %v", err)
+			}
+
+			if err := adapter.Clear(context.Background()); err != nil {
+				t.Fatalf("unable to clear policies: %v", err)
+			}
+
+			count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(context.Background())
+			if err != nil {
+				t.Fatalf("unable to count policies: %v", err)
+			}
+			if count != 0 {
+				t.Errorf("got %d policies, want 0", count)
+			}
+		})
+	}
+}
+
+func TestUpdateFilteredPoliciesWithoutNewRules(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+		{"alice", "data2", "write"},
+	}
+	if err := adapter.AddPolicies("p", "p", policies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	removed, err := adapter.UpdateFilteredPolicies("p", "p", nil, 0, "alice")
+	if err != nil {
+		t.Fatalf("unable to update filtered policies: %v", err)
+	}
+
+	want := [][]string{
+		{"p", "alice", "data1", "read"},
+		{"p", "alice", "data2", "write"},
+	}
+	if !util.SortedArray2DEquals(want, removed) {
+		t.Errorf("got removed %v, want %v", removed, want)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	got, _ := m.GetPolicy("p", "p")
+	if !util.Array2DEquals([][]string{{"bob", "data1", "read"}}, got) {
+		t.Errorf("got remaining %v, want [[bob data1 read]]", got)
+	}
+}
+
+func TestUpdateFilteredPoliciesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithRetry(50, func(int) time.Duration {
+		return 10 * time.Millisecond
+	}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data0", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	// Each update replaces the rule of alice, so every rule but the last one
+	// written must be returned as old by exactly one of them.
+	const n = 8
+	removed := make([][][]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			newRules := [][]string{{"alice", fmt.Sprintf("data%d", i+1), "read"}}
+			removed[i], errs[i] = adapter.UpdateFilteredPoliciesCtx(ctx, "p", "p", newRules, 0, "alice")
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]int)
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("update %d: unable to update filtered policies: %v", i, err)
+		}
+		for _, rule := range removed[i] {
+			seen[strings.Join(rule[1:], ",")]++
+		}
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	remaining, _ := m.GetPolicy("p", "p")
+	if len(remaining) != 1 {
+		t.Fatalf("got remaining %v, want a single rule", remaining)
+	}
+	seen[strings.Join(remaining[0], ",")]++
+
+	for i := 0; i <= n; i++ {
+		if rule := fmt.Sprintf("alice,data%d,read", i); seen[rule] != 1 {
+			t.Errorf("rule %s was seen %d times, want once", rule, seen[rule])
+		}
+	}
+}
+
+func TestLoadPolicyStream(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rules := make([][]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+	}
+	if err := adapter.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicy("g", "g", []string{"user1", "admin"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	buffered, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicy(buffered); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	streamed, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyStream(context.Background(), streamed); err != nil {
+		t.Fatalf("unable to stream policy: %v", err)
+	}
+
+	for _, sec := range []string{"p", "g"} {
+		want, _ := buffered.GetPolicy(sec, sec)
+		got, _ := streamed.GetPolicy(sec, sec)
+		if !util.Array2DEquals(want, got) {
+			t.Errorf("streamed %s rules differ from the buffered ones", sec)
+		}
+	}
+}
+
+func TestNewAdapterConcurrently(t *testing.T) {
+	t.Parallel()
+
+	db := initFileDB(t)
+
+	const n = 8
+	adapters := make([]*casbun.Adapter, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			adapters[i], errs[i] = casbun.NewAdapter(context.Background(), db)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("adapter %d: unable to create adapter: %v", i, err)
+		}
+	}
+
+	if err := adapters[0].AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+}
+
+func TestNewAdapterBusyDatabase(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// Without a busy timeout, SQLite fails at once while the database is
+	// locked by another connection.
+	dsn := "file:" + filepath.Join(t.TempDir(), "casbin.db") + "?_pragma=journal_mode(WAL)"
+	open := func() *bun.DB {
+		sqldb, err := sql.Open(sqliteshim.ShimName, dsn)
+		if err != nil {
+			t.Fatalf("unable to open database: %v", err)
+		}
+		db := bun.NewDB(sqldb, sqlitedialect.New())
+		t.Cleanup(func() {
+			_ = db.Close()
+		})
+		return db
+	}
+
+	locker, err := open().Conn(ctx)
+	if err != nil {
+		t.Fatalf("unable to get connection: %v", err)
+	}
+	if _, err := locker.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("unable to lock database: %v", err)
+	}
+	time.AfterFunc(50*time.Millisecond, func() {
+		_, _ = locker.ExecContext(ctx, "COMMIT")
+	})
+
+	const n = 4
+	dbs := make([]*bun.DB, n)
+	for i := range dbs {
+		dbs[i] = open()
+	}
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = casbun.NewAdapter(ctx, dbs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("adapter %d: unable to create adapter: %v", i, err)
+		}
+	}
+}
+
+func TestAutoCreateTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		opts      []casbun.CasbinBunOption
+		wantTable bool
+	}{
+		{name: "default", wantTable: true},
+		{name: "explicit", opts: []casbun.CasbinBunOption{casbun.WithAutoCreateTable()}, wantTable: true},
+		{name: "disabled", opts: []casbun.CasbinBunOption{casbun.DisableAutoCreateTable()}, wantTable: false},
+		{
+			name:      "enabled after disabled",
+			opts:      []casbun.CasbinBunOption{casbun.DisableAutoCreateTable(), casbun.WithAutoCreateTable()},
+			wantTable: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			adapter, err := casbun.NewAdapter(ctx, initDB(), tt.opts...)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			err = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+			if gotTable := err == nil; gotTable != tt.wantTable {
+				t.Errorf("got table %v (add error: %v), want %v", gotTable, err, tt.wantTable)
+			}
+		})
+	}
+}
+
+func TestNewAdapterWithoutInit(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapterWithoutInit(db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rule := []string{"alice", "data1", "read"}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err == nil {
+		t.Fatal("expected an error adding a policy before Init")
+	}
+
+	if err := adapter.Init(ctx); err != nil {
+		t.Fatalf("unable to init adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{rule})
+}
+
+func TestWithContext(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	ctx, cancel := context.WithCancel(context.Background())
+	adapter, err := casbun.NewAdapter(context.Background(), db, casbun.WithContext(ctx))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	cancel()
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicy(m); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if err := adapter.AddPolicy("p", "p", []string{"bob", "data1", "read"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := casbun.NewAdapter(context.Background(), db, casbun.WithContext(nil)); err == nil {
+		t.Errorf("expected an error for a nil context")
+	}
+}
+
+func TestLoadPolicyEmptyPType(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO casbin_policies (ptype, v0, v1, v2) VALUES ('', 'alice', 'data1', 'read')"); err != nil {
+		t.Fatalf("unable to insert row: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	err = adapter.LoadPolicyCtx(ctx, m)
+	if err == nil || !strings.Contains(err.Error(), "empty ptype") {
+		t.Errorf("got error %v, want an empty ptype error", err)
+	}
 }
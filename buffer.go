@@ -0,0 +1,269 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// writeBuffer holds the rules added through AddPolicy until they are flushed,
+// see WithWriteBuffer.
+type writeBuffer struct {
+	mu       sync.Mutex
+	size     int
+	interval time.Duration
+	policies []CasbinPolicy
+	timer    *time.Timer
+}
+
+// WithWriteBuffer buffers the rules added by AddPolicy and AddPolicyCtx, and
+// inserts them in batches once size rules are buffered, or at the latest
+// flush after the first one was buffered, for bursty workloads such as mass
+// onboarding. Flush and Close insert the buffered rules on demand.
+//
+// Every other operation, including the loads, flushes the buffer first, so it
+// observes the buffered rules. The before mutation hook runs when a rule is
+// buffered, while the after mutation hook runs and the event is sent when it
+// is inserted. A buffered rule is lost if the process exits without Close.
+// A buffered rule that the database rejects, such as one already stored, is
+// dropped from the buffer and reported to the handler set by
+// WithBackgroundErrorHandler, so that it does not fail the operations
+// flushing the buffer after it. The rules stay buffered if the flush fails as
+// a whole, for instance on a lost connection, and the next flush retries
+// them; the error of a background flush is then also reported to the
+// handler. Rules added within a transaction are not buffered. NewAdapter
+// fails if size or flush is not positive.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithWriteBuffer(100, time.Second))
+//	if err != nil {
+//	    return err
+//	}
+//	defer adapter.Close(ctx)
+func WithWriteBuffer(size int, flush time.Duration) CasbinBunOption {
+	return func(a *Adapter) {
+		if size <= 0 || flush <= 0 {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: write buffer size and flush interval must be positive"))
+			return
+		}
+		a.buffer = &writeBuffer{size: size, interval: flush}
+	}
+}
+
+// FlushError reports a buffered rule that a flush dropped as the database
+// rejected it, see WithWriteBuffer.
+type FlushError struct {
+	PType string
+	Rule  []string
+	Err   error
+}
+
+// Error returns the message of the wrapped error, prefixed with the rule.
+func (e *FlushError) Error() string {
+	return fmt.Sprintf("casbun: buffered rule %s %v rejected: %v", e.PType, e.Rule, e.Err)
+}
+
+// Unwrap returns the wrapped error.
+func (e *FlushError) Unwrap() error {
+	return e.Err
+}
+
+// Flush inserts the rules buffered by WithWriteBuffer, in a single
+// transaction. The rules stay buffered if it fails, while the rules rejected
+// by the database are dropped and their *FlushError returned, joined. It does
+// nothing without a write buffer.
+//
+// Example:
+//
+//	if err := adapter.Flush(ctx); err != nil {
+//	    return err
+//	}
+func (a *Adapter) Flush(ctx context.Context) (err error) {
+	defer wrapOpError("flush", "", &err)
+	defer a.observe("flush")()
+
+	rejected, err := a.flushBuffer(ctx)
+	return errors.Join(append([]error{err}, rejected...)...)
+}
+
+// Close flushes the rules buffered by WithWriteBuffer, see Flush. The adapter
+// must not buffer rules after Close. It does not close the database.
+//
+// Example:
+//
+//	defer adapter.Close(ctx)
+func (a *Adapter) Close(ctx context.Context) (err error) {
+	defer wrapOpError("close", "", &err)
+	if a.buffer == nil {
+		return nil
+	}
+
+	a.buffer.mu.Lock()
+	if a.buffer.timer != nil {
+		a.buffer.timer.Stop()
+		a.buffer.timer = nil
+	}
+	a.buffer.mu.Unlock()
+
+	return a.Flush(ctx)
+}
+
+// bufferPolicy buffers policy, flushing the buffer when it is full. It
+// reports whether policy was buffered, which it is not without a write buffer
+// or within a transaction.
+func (a *Adapter) bufferPolicy(ctx context.Context, policy CasbinPolicy) (bool, error) {
+	if a.buffer == nil {
+		return false, nil
+	}
+	if _, ok := a.txFor(ctx); ok {
+		return false, nil
+	}
+
+	b := a.buffer
+	b.mu.Lock()
+	b.policies = append(b.policies, policy)
+	full := len(b.policies) >= b.size
+	if !full && b.timer == nil {
+		b.timer = time.AfterFunc(b.interval, func() {
+			ctx, cancel := a.operationContext("flush")
+			defer cancel()
+			// The rules stay buffered on failure, so the next flush retries
+			// them.
+			if err := a.flushWrites(ctx); err != nil {
+				a.reportError(err)
+			}
+		})
+	}
+	b.mu.Unlock()
+
+	if full {
+		return true, a.flushWrites(ctx)
+	}
+	return true, nil
+}
+
+// flushWrites inserts the rules buffered by WithWriteBuffer, see flushBuffer,
+// before another operation. The rejected rules are only reported to the
+// handler set by WithBackgroundErrorHandler, so that they do not fail the
+// operation.
+func (a *Adapter) flushWrites(ctx context.Context) error {
+	_, err := a.flushBuffer(ctx)
+	return err
+}
+
+// flushBuffer inserts the rules buffered by WithWriteBuffer, putting them back
+// in the buffer if it fails. It runs on the primary database, even when ctx
+// carries a transaction, as the rules were added outside of it. A batch
+// rejected by the database is retried one rule at a time, each on a savepoint,
+// and the rules rejected in turn are dropped, reported to the handler set by
+// WithBackgroundErrorHandler, and returned.
+func (a *Adapter) flushBuffer(ctx context.Context) (rejected []error, err error) {
+	if a.buffer == nil {
+		return nil, nil
+	}
+
+	b := a.buffer
+	b.mu.Lock()
+	policies := b.policies
+	b.policies = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.mu.Unlock()
+
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	var inserted []CasbinPolicy
+	err = a.retryTransient(ctx, func(ctx context.Context) error {
+		inserted, rejected = nil, nil
+		return a.db.RunInTx(ctx, a.txOptions(), func(ctx context.Context, tx bun.Tx) error {
+			for start := 0; start < len(policies); start += insertBatchSize {
+				batch := policies[start:min(start+insertBatchSize, len(policies))]
+				err := a.insertOnSavepoint(ctx, tx, &batch)
+				if err == nil {
+					inserted = append(inserted, batch...)
+					continue
+				}
+				if isTransient(err) {
+					return err
+				}
+
+				for _, policy := range batch {
+					err := a.insertOnSavepoint(ctx, tx, &policy)
+					switch {
+					case err == nil:
+						inserted = append(inserted, policy)
+					case isTransient(err):
+						return err
+					default:
+						rejected = append(rejected, &FlushError{
+							PType: policy.PType,
+							Rule:  policy.filterValues(),
+							Err:   insertError(err),
+						})
+					}
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		b.mu.Lock()
+		b.policies = append(policies, b.policies...)
+		b.mu.Unlock()
+		return nil, tableError(err)
+	}
+
+	for _, err := range rejected {
+		a.reportError(err)
+	}
+	for _, event := range bufferedEvents(inserted) {
+		if err := a.notify(ctx, nil, event); err != nil {
+			return rejected, err
+		}
+	}
+	return rejected, nil
+}
+
+// insertOnSavepoint inserts the policy rows held by model on a savepoint of
+// tx, which is rolled back if the insert fails. A failed rollback is returned
+// as well, as tx can then not be used any further.
+func (a *Adapter) insertOnSavepoint(ctx context.Context, tx bun.Tx, model interface{}) error {
+	sp, err := tx.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := a.newInsert(ctx, sp, model).Exec(ctx); err != nil {
+		if rollbackErr := sp.Rollback(); rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+	return sp.Commit()
+}
+
+// bufferedEvents returns the events reporting the addition of policies, one
+// per run of consecutive policies of the same ptype.
+func bufferedEvents(policies []CasbinPolicy) []PolicyEvent {
+	var events []PolicyEvent
+	for _, policy := range policies {
+		if n := len(events); n > 0 && events[n-1].PType == policy.PType {
+			events[n-1].Rules = append(events[n-1].Rules, policy.filterValues())
+			continue
+		}
+		events = append(events, PolicyEvent{
+			Op:    EventAdd,
+			PType: policy.PType,
+			Rules: [][]string{policy.filterValues()},
+		})
+	}
+	return events
+}
@@ -0,0 +1,187 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestWriteBuffer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	spy := &querySpy{}
+	db.AddQueryHook(spy)
+
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithWriteBuffer(25, time.Hour))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	spy.queries = nil
+	for i := 0; i < 100; i++ {
+		if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{fmt.Sprintf("user%d", i), "data1", "read"}); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+	}
+	if got := countInserts(spy); got != 4 {
+		t.Errorf("got %d inserts for 100 policies, want 4", got)
+	}
+
+	// A load flushes the buffered rules first.
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"user1", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	if got := len(m["p"]["p"].Policy); got != 100 {
+		t.Errorf("got %d policies, want 100", got)
+	}
+	if got := len(m["g"]["g"].Policy); got != 1 {
+		t.Errorf("got %d grouping policies, want 1", got)
+	}
+
+	// Close flushes the buffered rules.
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.Close(ctx); err != nil {
+		t.Fatalf("failed to close adapter: %v", err)
+	}
+	var count int
+	if err := db.NewSelect().Table("casbin_policies").ColumnExpr("COUNT(*)").Scan(ctx, &count); err != nil {
+		t.Fatalf("failed to count policies: %v", err)
+	}
+	if count != 102 {
+		t.Errorf("got %d stored policies, want 102", count)
+	}
+	// The finalizer of the adapter closes db.
+	runtime.KeepAlive(adapter)
+}
+
+func TestWriteBufferInterval(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithWriteBuffer(100, 10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var count int
+		if err := db.NewSelect().Table("casbin_policies").ColumnExpr("COUNT(*)").Scan(ctx, &count); err != nil {
+			t.Fatalf("failed to count policies: %v", err)
+		}
+		if count == 1 {
+			// The finalizer of the adapter closes db.
+			runtime.KeepAlive(adapter)
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("buffered policy was not flushed after the interval")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWriteBufferRejectedRule(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	var (
+		mu      sync.Mutex
+		handled []error
+	)
+	adapter, err := casbun.NewAdapter(ctx, db,
+		casbun.WithWriteBuffer(100, time.Hour),
+		casbun.WithBackgroundErrorHandler(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, err)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.Flush(ctx); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	// The duplicate is rejected by the flush of the load, which still
+	// stores the other buffered rule and succeeds.
+	for _, rule := range [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}} {
+		if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+	}
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	if got := len(m["p"]["p"].Policy); got != 2 {
+		t.Errorf("got %d policies, want 2", got)
+	}
+
+	mu.Lock()
+	if len(handled) != 1 || !errors.Is(handled[0], casbun.ErrPolicyExists) {
+		t.Errorf("got handled errors %v, want one wrapping %v", handled, casbun.ErrPolicyExists)
+	}
+	var flushErr *casbun.FlushError
+	if len(handled) == 1 && errors.As(handled[0], &flushErr) && flushErr.Rule[0] != "alice" {
+		t.Errorf("got rejected rule %v, want alice's", flushErr.Rule)
+	}
+	mu.Unlock()
+
+	// The rejected rule was dropped, so it does not fail the next flush.
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.Flush(ctx); err != nil {
+		t.Errorf("failed to flush: %v", err)
+	}
+
+	// An explicit flush returns the rejected rules.
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.Flush(ctx); !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+}
+
+// countInserts returns the number of INSERT queries recorded by spy.
+func countInserts(spy *querySpy) int {
+	spy.mu.Lock()
+	defer spy.mu.Unlock()
+
+	count := 0
+	for _, query := range spy.queries {
+		if strings.HasPrefix(query, "INSERT") {
+			count++
+		}
+	}
+	return count
+}
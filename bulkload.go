@@ -0,0 +1,90 @@
+package casbun
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// BulkLoad adds policies to the stored policy, in batches inserted in a
+// single transaction, for large imports. With WithPTypeTable, each policy is
+// inserted in the table of its ptype. On dialects with transactional DDL,
+// PostgreSQL and SQLite, the secondary index on the ptype column, if created
+// by the adapter, see WithPtypeIndex, is dropped in the transaction before the
+// rows are inserted and recreated afterwards, which is much faster than
+// maintaining it row by row. If the load fails, the transaction is rolled
+// back, so no policy is added and the index is kept. The ids of policies are
+// not kept, the database assigns new ones, unless WithPreserveIDs is used.
+//
+// Example:
+//
+//	if err := adapter.BulkLoad(ctx, policies); err != nil {
+//	    return err
+//	}
+func (a *Adapter) BulkLoad(ctx context.Context, policies []CasbinPolicy) (err error) {
+	defer wrapOpError("bulk_load", "", &err)
+	defer a.observe("bulk_load")()
+
+	rows := make([]CasbinPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if !a.preserveIDs {
+			policy.ID = 0
+		}
+		rows = append(rows, policy)
+	}
+
+	dropIndex := a.ptypeIndex && !a.compositeKey && a.dialect().tableSwap
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				routes, grouped := a.routePolicies(rows)
+				for i, r := range routes {
+					rows := grouped[i]
+					if len(rows) == 0 {
+						continue
+					}
+
+					_, ptypeIndex := r.indexNames()
+					if dropIndex {
+						if err := r.dropIndex(ctx, tx, ptypeIndex); err != nil {
+							return err
+						}
+					}
+					for start := 0; start < len(rows); start += insertBatchSize {
+						batch := rows[start:min(start+insertBatchSize, len(rows))]
+						if _, err := r.newInsert(ctx, tx, &batch).
+							Exec(ctx); err != nil {
+							return insertError(err)
+						}
+					}
+					if dropIndex {
+						if err := r.createIndex(ctx, tx, "CREATE INDEX", ptypeIndex, "ptype"); err != nil {
+							return err
+						}
+					}
+					if r.preserveIDs {
+						if err := r.resetIDSequence(ctx, tx); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			},
+		)
+	})
+	return a.notify(ctx, err, PolicyEvent{Op: EventSave})
+}
+
+// dropIndex drops the index name of the policy table on tx, if it exists. The
+// index is qualified with the schema of the table, where it lives.
+func (a *Adapter) dropIndex(ctx context.Context, tx bun.Tx, name string) error {
+	if schema, _ := a.splitTableName(); schema != "" {
+		name = schema + "." + name
+	}
+	_, err := tx.NewRaw("DROP INDEX IF EXISTS ?", bun.Ident(name)).
+		Comment(a.queryComment(ctx)).
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,120 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestBulkLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	ptypeIndexes := func() int {
+		t.Helper()
+		var n int
+		if err := db.NewRaw(
+			"SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_casbin_ptype'",
+		).Scan(ctx, &n); err != nil {
+			t.Fatalf("unable to inspect schema: %v", err)
+		}
+		return n
+	}
+
+	policies := make([]casbun.CasbinPolicy, 0, 600)
+	for i := 0; i < 600; i++ {
+		policies = append(policies, casbun.CasbinPolicy{PType: "p", V0: fmt.Sprintf("user%d", i), V1: "data2", V2: "read"})
+	}
+	if err := adapter.BulkLoad(ctx, policies); err != nil {
+		t.Fatalf("unable to bulk load policies: %v", err)
+	}
+	if n := ptypeIndexes(); n != 1 {
+		t.Errorf("got %d ptype indexes after the load, want 1", n)
+	}
+
+	got, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if len(got) != 601 {
+		t.Fatalf("got %d stored policies, want 601", len(got))
+	}
+	if got[0].V0 != "alice" || got[1].V0 != "user0" || got[600].V0 != "user599" {
+		t.Errorf("got stored policies %+v, %+v and %+v, want alice, user0 and user599", got[0], got[1], got[600])
+	}
+
+	// A failed load adds nothing and still restores the index.
+	failing := []casbun.CasbinPolicy{
+		{PType: "p", V0: "bob", V1: "data3", V2: "read"},
+		{PType: "p", V0: "alice", V1: "data1", V2: "read"},
+	}
+	if err := adapter.BulkLoad(ctx, failing); !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+	if n := ptypeIndexes(); n != 1 {
+		t.Errorf("got %d ptype indexes after the failed load, want 1", n)
+	}
+	if got, err := adapter.Snapshot(ctx); err != nil || len(got) != 601 {
+		t.Errorf("got %d policies and error %v after the failed load, want 601", len(got), err)
+	}
+}
+
+func TestBulkLoadPTypeTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPTypeTable(map[string]string{"g": "casbin_groups"}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.BulkLoad(ctx, []casbun.CasbinPolicy{
+		{PType: "p", V0: "admin", V1: "data1", V2: "read"},
+		{PType: "g", V0: "alice", V1: "admin"},
+		{PType: "g", V0: "bob", V1: "admin"},
+	}); err != nil {
+		t.Fatalf("unable to bulk load policies: %v", err)
+	}
+
+	var policies, groups, indexes int
+	if err := db.NewRaw("SELECT count(*) FROM casbin_policies").Scan(ctx, &policies); err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	if err := db.NewRaw("SELECT count(*) FROM casbin_groups").Scan(ctx, &groups); err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	if policies != 1 || groups != 2 {
+		t.Errorf("got %d rows in casbin_policies and %d in casbin_groups, want 1 and 2", policies, groups)
+	}
+	if err := db.NewRaw(
+		"SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_casbin_groups_ptype'",
+	).Scan(ctx, &indexes); err != nil {
+		t.Fatalf("unable to inspect schema: %v", err)
+	}
+	if indexes != 1 {
+		t.Errorf("got %d ptype indexes on casbin_groups after the load, want 1", indexes)
+	}
+
+	if err := adapter.RemovePolicyCtx(ctx, "g", "g", []string{"bob", "admin"}); err != nil {
+		t.Fatalf("failed to remove grouping policy: %v", err)
+	}
+	if err := db.NewRaw("SELECT count(*) FROM casbin_groups").Scan(ctx, &groups); err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	if groups != 1 {
+		t.Errorf("got %d rows in casbin_groups after the removal, want 1", groups)
+	}
+}
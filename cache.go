@@ -0,0 +1,112 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/uptrace/bun"
+)
+
+// WithLoadCache caches the policy read by LoadPolicy for ttl, for read-heavy
+// applications reloading the whole policy often. Loads within ttl of the load
+// that filled the cache are served from it without any query. Every mutation
+// made through the adapter invalidates the cache, but changes made to the
+// table by other means, including other adapters, are only observed once ttl
+// expires. Loads running on a transaction carried by the context bypass the
+// cache. NewAdapter fails if ttl is not positive.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithLoadCache(5*time.Second))
+func WithLoadCache(ttl time.Duration) CasbinBunOption {
+	return func(a *Adapter) {
+		if ttl <= 0 {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: load cache ttl must be positive"))
+			return
+		}
+		a.cache = &loadCache{ttl: ttl}
+	}
+}
+
+// loadCache holds the policies read by the last full load.
+type loadCache struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	policies []CasbinPolicy
+	expires  time.Time
+	// generation counts the invalidations, so that a load racing with a
+	// mutation does not cache the policies read before it.
+	generation uint64
+}
+
+// get returns the cached policies, if they have not expired, and the current
+// generation of the cache.
+func (c *loadCache) get() ([]CasbinPolicy, bool, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.policies == nil || time.Now().After(c.expires) {
+		return nil, false, c.generation
+	}
+	return c.policies, true, c.generation
+}
+
+// set caches policies, unless the cache was invalidated since generation.
+func (c *loadCache) set(policies []CasbinPolicy, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+	c.policies = policies
+	c.expires = time.Now().Add(c.ttl)
+}
+
+// invalidate drops the cached policies. It does nothing on a nil cache.
+func (c *loadCache) invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.policies = nil
+	c.generation++
+}
+
+// loadCachedPolicy loads every policy rule into model from the cache, filling
+// it from the storage when it is empty or expired.
+func (a *Adapter) loadCachedPolicy(ctx context.Context, model model.Model) error {
+	if _, ok := a.txFor(ctx); ok {
+		return a.loadPolicy(ctx, model, Filter{})
+	}
+
+	policies, ok, generation := a.cache.get()
+	if !ok {
+		policies = make([]CasbinPolicy, 0)
+		err := a.readOnlyLoad(ctx, func(ctx context.Context) error {
+			for _, r := range a.routes() {
+				routed, err := r.selectLoadedPolicies(ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
+					return q
+				})
+				if err != nil {
+					return err
+				}
+				policies = append(policies, routed...)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		a.cache.set(policies, generation)
+	}
+
+	return a.loadPolicyRecords(policies, model)
+}
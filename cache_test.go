@@ -0,0 +1,79 @@
+package casbun_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+)
+
+// selectCounter counts the SELECT queries run on a database.
+type selectCounter struct {
+	selects atomic.Int32
+}
+
+func (c *selectCounter) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (c *selectCounter) AfterQuery(_ context.Context, event *bun.QueryEvent) {
+	if strings.HasPrefix(event.Query, "SELECT") {
+		c.selects.Add(1)
+	}
+}
+
+func TestLoadCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	counter := &selectCounter{}
+	db.AddQueryHook(counter)
+
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithLoadCache(time.Hour))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	load := func() [][]string {
+		t.Helper()
+
+		m, _ := model.NewModelFromString(modelStr)
+		if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+			t.Fatalf("unable to load policy: %v", err)
+		}
+		return m["p"]["p"].Policy
+	}
+
+	counter.selects.Store(0)
+	load()
+	if got := load(); len(got) != 1 {
+		t.Errorf("got policy %v from the cache, want 1 rule", got)
+	}
+	if got := counter.selects.Load(); got != 1 {
+		t.Errorf("got %d queries for two loads, want 1", got)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	counter.selects.Store(0)
+	if got := load(); len(got) != 2 {
+		t.Errorf("got policy %v after adding a rule, want 2 rules", got)
+	}
+	if got := counter.selects.Load(); got != 1 {
+		t.Errorf("got %d queries after invalidation, want 1", got)
+	}
+
+	if _, err := casbun.NewAdapter(ctx, db, casbun.WithLoadCache(0)); err == nil {
+		t.Errorf("expected an error for a zero ttl")
+	}
+}
@@ -0,0 +1,81 @@
+package casbun
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// VerifyConsistency compares the number of stored rules of each ptype with
+// the number of rules of that ptype held by model, across all its sections,
+// as a sanity check after SavePolicy. It returns an error wrapping
+// ErrInconsistentPolicy that lists every ptype whose counts differ. Only the
+// ptypes allowed by WithPTypeFilter are compared, and model should hold the
+// whole policy rather than a filtered load of it.
+//
+// Example:
+//
+//	if err := adapter.SavePolicyCtx(ctx, model); err != nil {
+//	    return err
+//	}
+//	if err := adapter.VerifyConsistency(ctx, model); err != nil {
+//	    log.Printf("policy storage is out of sync: %v", err)
+//	}
+func (a *Adapter) VerifyConsistency(ctx context.Context, model model.Model) (err error) {
+	defer wrapOpError("verify_consistency", "", &err)
+	defer a.observe("verify_consistency")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return err
+	}
+
+	stored := make(map[string]int)
+	for _, r := range a.routes() {
+		var counts []struct {
+			PType string `bun:"ptype"`
+			Count int    `bun:"count"`
+		}
+		if err := r.newSelect(ctx, r.reader(ctx), (*CasbinPolicy)(nil)).
+			ExcludeColumn("*").
+			Column("ptype").
+			ColumnExpr("count(*) AS count").
+			ApplyQueryBuilder(r.allowedPTypes).
+			Group("ptype").
+			Scan(ctx, &counts); err != nil {
+			return tableError(err)
+		}
+		for _, c := range counts {
+			stored[c.PType] += c.Count
+		}
+	}
+
+	loaded := make(map[string]int)
+	for _, policy := range a.allowedPolicies(modelPolicies(model)) {
+		loaded[policy.PType]++
+	}
+
+	ptypes := make([]string, 0, len(stored)+len(loaded))
+	for ptype := range stored {
+		ptypes = append(ptypes, ptype)
+	}
+	for ptype := range loaded {
+		if _, ok := stored[ptype]; !ok {
+			ptypes = append(ptypes, ptype)
+		}
+	}
+	sort.Strings(ptypes)
+
+	var problems []string
+	for _, ptype := range ptypes {
+		if stored[ptype] != loaded[ptype] {
+			problems = append(problems, fmt.Sprintf("%s has %d stored rules and %d in the model", ptype, stored[ptype], loaded[ptype]))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInconsistentPolicy, strings.Join(problems, "; "))
+	}
+	return nil
+}
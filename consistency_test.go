@@ -0,0 +1,49 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestVerifyConsistency(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	_ = m.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	_ = m.AddPolicy("p", "p", []string{"bob", "data2", "write"})
+	_ = m.AddPolicy("g", "g", []string{"alice", "admin"})
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+	if err := adapter.VerifyConsistency(ctx, m); err != nil {
+		t.Fatalf("got error %v after saving, want none", err)
+	}
+
+	// A grouping rule deleted behind the adapter's back.
+	if _, err := db.NewDelete().
+		Model((*casbun.CasbinPolicy)(nil)).
+		Where("ptype = ?", "g").
+		Exec(ctx); err != nil {
+		t.Fatalf("unable to delete grouping rule: %v", err)
+	}
+
+	err = adapter.VerifyConsistency(ctx, m)
+	if !errors.Is(err, casbun.ErrInconsistentPolicy) {
+		t.Fatalf("got error %v, want %v", err, casbun.ErrInconsistentPolicy)
+	}
+	if want := "g has 0 stored rules and 1 in the model"; !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want it to contain %q", err, want)
+	}
+}
@@ -0,0 +1,63 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// WithDeleteBatchSize makes RemoveFilteredPolicy delete the matched rules in
+// batches of at most n rows, each deleted by its own statement, so that a
+// filter matching millions of rows neither holds its locks for long nor
+// exceeds the transaction limits of the database. The removal is then no
+// longer atomic: if it fails, the batches already deleted stay deleted, and
+// readers may observe a partial removal. Calling it again with the same filter
+// removes the remaining rules. Within a transaction carried by the context,
+// the batches are part of it.
+//
+// It requires an id column, and NewAdapter fails if it is combined with
+// WithCompositeKey or if n is not positive.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithDeleteBatchSize(10000))
+func WithDeleteBatchSize(n int) CasbinBunOption {
+	return func(a *Adapter) {
+		if n <= 0 {
+			a.optionErr = errors.Join(a.optionErr, fmt.Errorf("casbun: delete batch size %d is not positive", n))
+			return
+		}
+		a.deleteBatchSize = n
+	}
+}
+
+// deleteBatches deletes the rows matching condition in batches of the size set
+// by WithDeleteBatchSize, until no matching row is left.
+func (a *Adapter) deleteBatches(ctx context.Context, condition func(bun.QueryBuilder) bun.QueryBuilder) error {
+	db := a.conn(ctx)
+	for {
+		batch := a.newSelect(ctx, db, (*CasbinPolicy)(nil)).
+			ExcludeColumn("*").
+			Column("id").
+			ApplyQueryBuilder(condition).
+			Order("id").
+			Limit(a.deleteBatchSize)
+
+		// The derived table lets MySQL limit the rows of the subquery.
+		res, err := a.newDelete(ctx, db).
+			Where("? IN (SELECT ? FROM (?) AS batch)", bun.Ident("id"), bun.Ident("id"), batch).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n < int64(a.deleteBatchSize) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package casbun_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestDeleteBatchSize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithDeleteBatchSize(100))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	// Several full batches and a partial one, interleaved with kept rules.
+	rules := make([][]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		obj := "data1"
+		if i%4 == 0 {
+			obj = "data2"
+		}
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), obj, "read"})
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	if err := adapter.RemoveFilteredPolicyCtx(ctx, "p", "p", 1, "data1"); err != nil {
+		t.Fatalf("failed to remove filtered policy: %v", err)
+	}
+
+	removed, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Where("v1 = ?", "data1").Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("got %d matched rules left, want 0", removed)
+	}
+	kept, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Where("v1 = ?", "data2").Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if kept != 250 {
+		t.Errorf("got %d unmatched rules, want 250", kept)
+	}
+
+	if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithDeleteBatchSize(0)); err == nil {
+		t.Errorf("expected an error for a delete batch size of 0")
+	}
+}
@@ -0,0 +1,127 @@
+package casbun
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// supportedDialects lists the dialects the statements of the adapter are
+// written for.
+var supportedDialects = []dialect.Name{
+	dialect.PG,
+	dialect.SQLite,
+	dialect.MySQL,
+	dialect.MSSQL,
+	dialect.Oracle,
+}
+
+// checkDialect returns an error wrapping ErrUnsupportedDialect unless db has
+// one of the supported dialects.
+func checkDialect(db *bun.DB) error {
+	if db.Dialect() == nil {
+		return fmt.Errorf("%w: the database has no dialect, want one of %v", ErrUnsupportedDialect, supportedDialects)
+	}
+	if name := db.Dialect().Name(); !slices.Contains(supportedDialects, name) {
+		return fmt.Errorf("%w: %s, want one of %v", ErrUnsupportedDialect, name, supportedDialects)
+	}
+	return nil
+}
+
+// dialectFeatures classifies the SQL features of a dialect that the statements
+// of the adapter depend on, so that the dialect is only switched on here.
+type dialectFeatures struct {
+	name dialect.Name
+
+	// indexIfNotExists reports support for CREATE INDEX IF NOT EXISTS.
+	indexIfNotExists bool
+	// onConflictDoNothing and insertIgnore report the clause skipping the
+	// inserted rows that violate a unique index, if any.
+	onConflictDoNothing bool
+	insertIgnore        bool
+	// maxIndexBytes bounds the length of an index key, if not 0.
+	maxIndexBytes int
+	// serialSequence reports ids generated by a sequence, which inserts with
+	// explicit ids do not advance.
+	serialSequence bool
+	// tableSwap reports transactional DDL, which is rolled back with the
+	// transaction.
+	tableSwap bool
+	// renameSwap reports that a table can be dropped and replaced by a
+	// renamed one in a transaction without failing the concurrent readers
+	// waiting on it.
+	renameSwap bool
+	// selectForUpdate reports support for SELECT ... FOR UPDATE, locking the
+	// selected rows until the end of the transaction.
+	selectForUpdate bool
+
+	// reindex rebuilds the indexes of the table given as argument, and vacuum
+	// reclaims its storage, if supported.
+	reindex string
+	vacuum  string
+}
+
+// dialectOf returns the features of the dialect name.
+func dialectOf(name dialect.Name) dialectFeatures {
+	switch name {
+	case dialect.SQLite:
+		return dialectFeatures{
+			name:                name,
+			indexIfNotExists:    true,
+			onConflictDoNothing: true,
+			tableSwap:           true,
+			renameSwap:          true,
+			reindex:             "REINDEX ?",
+			// SQLite has no per-table vacuum.
+			vacuum: "VACUUM",
+		}
+	case dialect.PG:
+		return dialectFeatures{
+			name:                name,
+			indexIfNotExists:    true,
+			onConflictDoNothing: true,
+			serialSequence:      true,
+			tableSwap:           true,
+			selectForUpdate:     true,
+			reindex:             "REINDEX TABLE ?",
+			vacuum:              "VACUUM ANALYZE ?",
+		}
+	case dialect.MySQL:
+		return dialectFeatures{
+			name:            name,
+			insertIgnore:    true,
+			maxIndexBytes:   mysqlMaxIndexBytes,
+			selectForUpdate: true,
+			reindex:         "OPTIMIZE TABLE ?",
+			vacuum:          "OPTIMIZE TABLE ?",
+		}
+	case dialect.MSSQL:
+		return dialectFeatures{
+			name:    name,
+			reindex: "ALTER INDEX ALL ON ? REBUILD",
+		}
+	case dialect.Oracle:
+		return dialectFeatures{
+			name:            name,
+			selectForUpdate: true,
+			// Oracle maintains its indexes, so only the statistics of the
+			// table are refreshed.
+			reindex: "ANALYZE TABLE ? COMPUTE STATISTICS",
+		}
+	default:
+		return dialectFeatures{name: name}
+	}
+}
+
+// Dialect returns the name of the dialect of the adapter's database, such as
+// "sqlite", "pg" or "mysql", e.g. to check that the expected driver is wired.
+func (a *Adapter) Dialect() string {
+	return a.db.Dialect().Name().String()
+}
+
+// dialect returns the features of the adapter's dialect.
+func (a *Adapter) dialect() dialectFeatures {
+	return dialectOf(a.db.Dialect().Name())
+}
@@ -0,0 +1,97 @@
+package casbun_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// unknownDialect is a custom dialect the adapter does not support.
+type unknownDialect struct {
+	*sqlitedialect.Dialect
+}
+
+func (unknownDialect) Name() dialect.Name {
+	return dialect.Invalid
+}
+
+func TestDialect(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := casbun.NewAdapter(context.Background(), initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if got := adapter.Dialect(); got != "sqlite" {
+		t.Errorf("got dialect %q, want %q", got, "sqlite")
+	}
+}
+
+func TestUnsupportedDialect(t *testing.T) {
+	t.Parallel()
+
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:?mode=memory")
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	db := bun.NewDB(sqldb, unknownDialect{sqlitedialect.New()})
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = casbun.NewAdapter(context.Background(), db)
+	if !errors.Is(err, casbun.ErrUnsupportedDialect) {
+		t.Fatalf("got error %v, want %v", err, casbun.ErrUnsupportedDialect)
+	}
+	if want := "want one of [pg sqlite mysql mssql oracle]"; !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want it to contain %q", err, want)
+	}
+}
+
+func TestReservedColumnNames(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db,
+		casbun.WithTableName("select"),
+		casbun.WithPriorityColumn("order"),
+		casbun.WithMetadataColumn("group"),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyWithMetadata(ctx, "p", []string{"alice", "data1", "read"}, "onboarding"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"bob", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.UpdatePoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}}, [][]string{{"alice", "data1", "write"}}); err != nil {
+		t.Fatalf("failed to update policies: %v", err)
+	}
+	if err := adapter.RemoveFilteredPolicyCtx(ctx, "p", "p", 1, "data2"); err != nil {
+		t.Fatalf("failed to remove filtered policy: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadFilteredPolicyCtx(ctx, m, casbun.Filter{PType: []string{"p"}, V0: []string{"alice", "bob"}}); err != nil {
+		t.Fatalf("unable to load filtered policy: %v", err)
+	}
+	want := [][]string{{"alice", "data1", "write"}, {"bob", "data1", "read"}}
+	if got, _ := m.GetPolicy("p", "p"); !util.Array2DEquals(want, got) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,268 @@
+package casbun
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPolicyExists is returned when a policy rule can not be stored because an
+// identical rule already exists.
+var ErrPolicyExists = errors.New("casbun: policy already exists")
+
+// ErrInvalidFieldIndex is returned when the field index and field values of a
+// filtered operation do not fit in the v0 to v5 columns.
+var ErrInvalidFieldIndex = errors.New("casbun: invalid field index")
+
+// ErrEmptyRule is returned when adding a rule without any non-empty value,
+// unless WithAllowEmptyRules is used.
+var ErrEmptyRule = errors.New("casbun: empty rule")
+
+// ErrEmptySave is returned by SavePolicy when the model holds no rule and
+// WithRefuseEmptySave is used.
+var ErrEmptySave = errors.New("casbun: refusing to save an empty policy")
+
+// ErrSchemaMismatch is returned by ValidateSchema when the policy table does
+// not match the schema expected by the adapter.
+var ErrSchemaMismatch = errors.New("casbun: schema mismatch")
+
+// ErrVersionConflict is returned by UpdatePolicyIfVersion when the stored
+// version of the rule differs from the expected one.
+var ErrVersionConflict = errors.New("casbun: version conflict")
+
+// ErrInconsistentPolicy is returned by VerifyConsistency when the number of
+// stored rules of a ptype differs from the number held by the model.
+var ErrInconsistentPolicy = errors.New("casbun: inconsistent policy")
+
+// ErrTableMissing is returned when the policy table does not exist, e.g.
+// because it was dropped while the adapter was in use.
+var ErrTableMissing = errors.New("casbun: policy table does not exist")
+
+// ErrUnsupportedDialect is returned by NewAdapter when the database has no
+// dialect, or one the adapter does not support.
+var ErrUnsupportedDialect = errors.New("casbun: unsupported dialect")
+
+// OpError is the error returned by the methods of the adapter. It records the
+// method that failed and the ptype of the rules it operated on, if any, while
+// errors.Is and errors.As still match the error it wraps, such as a sentinel
+// error of the package or a driver error.
+//
+// Example:
+//
+//	var opErr *casbun.OpError
+//	if errors.As(err, &opErr) {
+//	    log.Printf("policy operation %s on %s failed: %v", opErr.Op, opErr.PType, opErr.Err)
+//	}
+type OpError struct {
+	// Op is the name of the operation, such as add_policy.
+	Op string
+	// PType is the ptype of the rules of the operation, empty for operations
+	// on the whole policy.
+	PType string
+	Err   error
+}
+
+// Error returns the message of the wrapped error, prefixed with the operation
+// and the ptype.
+func (e *OpError) Error() string {
+	msg := strings.TrimPrefix(e.Err.Error(), "casbun: ")
+	if e.PType == "" {
+		return fmt.Sprintf("casbun: %s: %s", e.Op, msg)
+	}
+	return fmt.Sprintf("casbun: %s %s: %s", e.Op, e.PType, msg)
+}
+
+// Unwrap returns the wrapped error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// wrapOpError wraps the error pointed to by err in an OpError for op and
+// ptype, unless it is nil or already an OpError, such as one returned by a
+// method the operation delegated to.
+func wrapOpError(op, ptype string, err *error) {
+	var opErr *OpError
+	if *err == nil || errors.As(*err, &opErr) {
+		return
+	}
+	*err = &OpError{Op: op, PType: ptype, Err: *err}
+}
+
+// uniqueViolationMessages holds the messages used by the supported drivers to
+// report a unique constraint violation.
+var uniqueViolationMessages = []string{
+	"UNIQUE constraint failed",            // sqlite
+	"duplicate key value violates unique", // postgres
+	"SQLSTATE 23505",                      // postgres (pgx)
+	"Duplicate entry",                     // mysql
+	"Cannot insert duplicate key",         // mssql
+	"unique constraint (",                 // oracle
+}
+
+// isUniqueViolation reports whether err was caused by the unique index on the
+// policy table.
+func isUniqueViolation(err error) bool {
+	var sqlState interface{ SQLState() string }
+	if errors.As(err, &sqlState) && sqlState.SQLState() == "23505" {
+		return true
+	}
+
+	msg := err.Error()
+	for _, s := range uniqueViolationMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// insertError maps a driver error returned by an insert to ErrPolicyExists
+// when it was caused by a duplicate rule. The driver error is kept in the
+// chain so it can still be inspected with errors.As.
+func insertError(err error) error {
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: %w", ErrPolicyExists, err)
+	}
+	return err
+}
+
+// duplicateIndexMessages holds the messages used by the drivers of dialects
+// without CREATE INDEX IF NOT EXISTS to report an existing index.
+var duplicateIndexMessages = []string{
+	"Duplicate key name",   // mysql
+	"already exists",       // mssql
+	"name is already used", // oracle
+}
+
+// isDuplicateIndex reports whether err was caused by creating an index that
+// already exists.
+func isDuplicateIndex(err error) bool {
+	msg := err.Error()
+	for _, s := range duplicateIndexMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// transientSQLStates holds the SQLSTATE codes of errors that may succeed when
+// the operation is retried.
+var transientSQLStates = []string{
+	"40001", // serialization failure
+	"40P01", // deadlock detected (postgres)
+}
+
+// transientMessages holds the messages used by the supported drivers to report
+// an error that may succeed when the operation is retried.
+var transientMessages = []string{
+	"SQLITE_BUSY",                // sqlite
+	"database is locked",         // sqlite
+	"could not serialize access", // postgres
+	"deadlock detected",          // postgres
+	"Deadlock found",             // mysql
+	"Lock wait timeout exceeded", // mysql
+	"was deadlocked on lock",     // mssql
+	"SQLSTATE 40001",             // postgres (pgx)
+	"SQLSTATE 40P01",             // postgres (pgx)
+}
+
+// isTransient reports whether err is a transient error, which WithRetry
+// retries.
+func isTransient(err error) bool {
+	var sqlState interface{ SQLState() string }
+	if errors.As(err, &sqlState) {
+		for _, code := range transientSQLStates {
+			if sqlState.SQLState() == code {
+				return true
+			}
+		}
+	}
+
+	msg := err.Error()
+	for _, s := range transientMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// missingTableSQLStates holds the SQLSTATE codes of errors reporting a missing
+// table.
+var missingTableSQLStates = []string{
+	"42P01", // undefined table (postgres)
+	"42S02", // base table not found (mysql)
+}
+
+// missingTableMessages holds the messages used by the supported drivers to
+// report a missing table.
+var missingTableMessages = []string{
+	"no such table",       // sqlite
+	"SQLSTATE 42P01",      // postgres (pgx)
+	"Error 1146",          // mysql
+	"Invalid object name", // mssql
+	"ORA-00942",           // oracle
+}
+
+// isMissingTable reports whether err was caused by a missing table.
+func isMissingTable(err error) bool {
+	var sqlState interface{ SQLState() string }
+	if errors.As(err, &sqlState) {
+		for _, code := range missingTableSQLStates {
+			if sqlState.SQLState() == code {
+				return true
+			}
+		}
+	}
+
+	msg := err.Error()
+	for _, s := range missingTableMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tableError maps a driver error to ErrTableMissing when it was caused by a
+// missing table, keeping the driver error in the chain.
+func tableError(err error) error {
+	if err != nil && !errors.Is(err, ErrTableMissing) && isMissingTable(err) {
+		return fmt.Errorf("%w: %w", ErrTableMissing, err)
+	}
+	return err
+}
+
+// WithBackgroundErrorHandler calls handle with the errors of the work the
+// adapter does in the background, which has no caller to return them to: a
+// *FlushError for each rule of the buffer set by WithWriteBuffer rejected by
+// the database, the error of a failed background flush, and the error of a
+// failed reload started by StartAutoReload. handle may be called
+// concurrently.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db,
+//	    WithWriteBuffer(100, time.Second),
+//	    WithBackgroundErrorHandler(func(err error) {
+//	        log.Printf("casbin policy: %v", err)
+//	    }),
+//	)
+func WithBackgroundErrorHandler(handle func(err error)) CasbinBunOption {
+	return func(a *Adapter) {
+		a.errorHandler = handle
+	}
+}
+
+// reportError passes err to the handler set by WithBackgroundErrorHandler, if
+// any.
+func (a *Adapter) reportError(err error) {
+	if a.errorHandler != nil {
+		a.errorHandler(err)
+	}
+}
@@ -0,0 +1,77 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestAddPolicyDuplicate(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rule := []string{"alice", "data1", "read"}
+	if err := adapter.AddPolicy("p", "p", rule); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	err = adapter.AddPolicy("p", "p", rule)
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got %v, want %v", err, casbun.ErrPolicyExists)
+	}
+
+	err = adapter.AddPolicies("p", "p", [][]string{{"bob", "data1", "read"}, rule})
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got %v, want %v", err, casbun.ErrPolicyExists)
+	}
+}
+
+func TestOpError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rule := []string{"alice", "data1", "read"}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	err = adapter.AddPolicy("p", "p", rule)
+	var opErr *casbun.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("got error %v, want an OpError", err)
+	}
+	if opErr.Op != "add_policy" || opErr.PType != "p" {
+		t.Errorf("got operation %q on %q, want add_policy on p", opErr.Op, opErr.PType)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "casbun: add_policy p: ") || !strings.Contains(msg, "UNIQUE constraint failed") {
+		t.Errorf("got message %q, want the operation and the driver error", msg)
+	}
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got %v, want %v", err, casbun.ErrPolicyExists)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	m, _ := model.NewModelFromString(modelStr)
+	err = adapter.LoadPolicyCtx(cancelled, m)
+	if !errors.As(err, &opErr) || opErr.Op != "load_policy" || opErr.PType != "" {
+		t.Errorf("got error %v, want an OpError of load_policy", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,95 @@
+package casbun
+
+import "context"
+
+// EventOp identifies the kind of change reported by a PolicyEvent.
+type EventOp int
+
+const (
+	// EventAdd reports rules added to the storage.
+	EventAdd EventOp = iota + 1
+	// EventRemove reports rules removed from the storage.
+	EventRemove
+	// EventRemoveFiltered reports the removal of the rules matching a filter.
+	EventRemoveFiltered
+	// EventUpdate reports rules replaced by new rules.
+	EventUpdate
+	// EventSave reports the replacement of the whole stored policy.
+	EventSave
+	// EventClear reports the removal of every stored rule.
+	EventClear
+)
+
+// String returns the name of the operation.
+func (op EventOp) String() string {
+	switch op {
+	case EventAdd:
+		return "add"
+	case EventRemove:
+		return "remove"
+	case EventRemoveFiltered:
+		return "remove_filtered"
+	case EventUpdate:
+		return "update"
+	case EventSave:
+		return "save"
+	case EventClear:
+		return "clear"
+	default:
+		return "unknown"
+	}
+}
+
+// PolicyEvent describes a change made to the stored policy.
+type PolicyEvent struct {
+	Op    EventOp
+	PType string
+	// Rules holds the added or removed rules, or the new rules of an update.
+	Rules [][]string
+	// OldRules holds the rules replaced by an update.
+	OldRules [][]string
+	// FieldIndex and FieldValues hold the filter of EventRemoveFiltered.
+	FieldIndex  int
+	FieldValues []string
+}
+
+// WithEventChannel sends a PolicyEvent on ch after each successful change made
+// through the adapter. Events are sent without blocking and dropped when ch
+// is not ready, so ch should be buffered according to the expected rate of
+// changes. Changes made on a transaction carried by the context are reported
+// once their statements succeed, even if the transaction is later rolled back.
+//
+// Example:
+//
+//	events := make(chan casbun.PolicyEvent, 100)
+//	adapter, err := NewAdapter(ctx, db, WithEventChannel(events))
+func WithEventChannel(ch chan<- PolicyEvent) CasbinBunOption {
+	return func(a *Adapter) {
+		a.events = ch
+	}
+}
+
+// notify reports event when err is nil, to the hook set by WithAfterMutation
+// and then on the channel set by WithEventChannel, and returns err or the
+// error of the hook. As every mutation ends with it, it also invalidates the
+// cache of WithLoadCache, even on failure since the mutation may have
+// partially applied.
+func (a *Adapter) notify(ctx context.Context, err error, event PolicyEvent) error {
+	a.cache.invalidate()
+
+	if err != nil {
+		return err
+	}
+	if err := a.runHook(ctx, a.afterMutation, event); err != nil {
+		return err
+	}
+	if a.events == nil {
+		return nil
+	}
+
+	select {
+	case a.events <- event:
+	default:
+	}
+	return nil
+}
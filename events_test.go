@@ -0,0 +1,59 @@
+package casbun_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestEventChannel(t *testing.T) {
+	t.Parallel()
+
+	events := make(chan casbun.PolicyEvent, 10)
+	adapter, err := casbun.NewAdapter(context.Background(), initDB(), casbun.WithEventChannel(events))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	if _, err := e.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if _, err := e.AddPolicies([][]string{{"bob", "data1", "read"}, {"carol", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if _, err := e.RemovePolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("failed to remove policy: %v", err)
+	}
+	if _, err := e.RemoveFilteredPolicy(1, "data2"); err != nil {
+		t.Fatalf("failed to remove filtered policy: %v", err)
+	}
+	// A failed change is not reported.
+	if err := adapter.AddPolicy("p", "p", []string{"bob", "data1", "read"}); err == nil {
+		t.Fatalf("expected an error for a duplicate policy")
+	}
+	close(events)
+
+	want := []casbun.PolicyEvent{
+		{Op: casbun.EventAdd, PType: "p", Rules: [][]string{{"alice", "data1", "read"}}},
+		{Op: casbun.EventAdd, PType: "p", Rules: [][]string{{"bob", "data1", "read"}, {"carol", "data2", "write"}}},
+		{Op: casbun.EventRemove, PType: "p", Rules: [][]string{{"alice", "data1", "read"}}},
+		{Op: casbun.EventRemoveFiltered, PType: "p", FieldIndex: 1, FieldValues: []string{"data2"}},
+	}
+	got := make([]casbun.PolicyEvent, 0, len(want))
+	for event := range events {
+		got = append(got, event)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %+v, want %+v", got, want)
+	}
+}
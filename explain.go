@@ -0,0 +1,67 @@
+package casbun
+
+import (
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// ExplainRemoveFilteredPolicy returns the statement RemoveFilteredPolicy would
+// run for the same arguments, without running it, to debug filters that do not
+// match the expected rules. The arguments are rendered inline by Bun, so the
+// statement can be run as is, e.g. as a SELECT after editing it.
+//
+// Example:
+//
+//	query, err := adapter.ExplainRemoveFilteredPolicy("p", 1, "data1")
+//	if err != nil {
+//	    return err
+//	}
+//	log.Print(query)
+func (a *Adapter) ExplainRemoveFilteredPolicy(ptype string, fieldIndex int, fieldValues ...string) (_ string, err error) {
+	defer wrapOpError("explain_remove_filtered_policy", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.ExplainRemoveFilteredPolicy(ptype, fieldIndex, fieldValues...)
+	}
+
+	condition, err := a.filteredCondition(ptype, fieldIndex, fieldValues)
+	if err != nil {
+		return "", err
+	}
+	return a.newDelete(a.ctx, a.db).
+		ApplyQueryBuilder(condition).
+		String(), nil
+}
+
+// ExplainLoadFilteredPolicy returns the statement LoadFilteredPolicy would run
+// for filter, without running it, like ExplainRemoveFilteredPolicy. The
+// filter must be a Filter or a *Filter, and a nil filter explains the load of
+// all policy rules. When WithPTypeTable is used, the
+// statements loading each table are separated by semicolons.
+//
+// Example:
+//
+//	query, err := adapter.ExplainLoadFilteredPolicy(casbun.Filter{PType: []string{"p"}, V0: []string{"alice"}})
+func (a *Adapter) ExplainLoadFilteredPolicy(filter interface{}) (_ string, err error) {
+	defer wrapOpError("explain_load_filtered_policy", "", &err)
+
+	var f Filter
+	if !isNilFilter(filter) {
+		if f, err = toFilter(filter); err != nil {
+			return "", err
+		}
+	}
+
+	queries := make([]string, 0, 1)
+	for _, r := range a.routes() {
+		var dest interface{} = &[]CasbinPolicy{}
+		if r.newRow != nil {
+			dest = r.newModelRows(nil)
+		}
+		query := r.loadQuery(a.ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
+			return f.apply(q, r.caseInsensitive)
+		}, dest)
+		queries = append(queries, query.String())
+	}
+	return strings.Join(queries, ";\n"), nil
+}
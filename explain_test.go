@@ -0,0 +1,68 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestExplainRemoveFilteredPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	query, err := adapter.ExplainRemoveFilteredPolicy("p", 1, "data1", "", "allow")
+	if err != nil {
+		t.Fatalf("unable to explain remove: %v", err)
+	}
+	for _, want := range []string{`DELETE FROM "casbin_policies"`, `"ptype" = 'p'`, `"v1" = 'data1'`, `"v3" = 'allow'`} {
+		if !strings.Contains(query, want) {
+			t.Errorf("got query %q, want it to contain %q", query, want)
+		}
+	}
+	if strings.Contains(query, `"v2"`) {
+		t.Errorf("got query %q, want no condition on the empty field", query)
+	}
+
+	// The explained statement is not run.
+	rules, err := adapter.GetFilteredPolicy(ctx, "p", 0, "alice")
+	if err != nil {
+		t.Fatalf("unable to get filtered policy: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Errorf("got %d rules, want 1", len(rules))
+	}
+
+	if _, err := adapter.ExplainRemoveFilteredPolicy("p", 6, "data1"); !errors.Is(err, casbun.ErrInvalidFieldIndex) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrInvalidFieldIndex)
+	}
+}
+
+func TestExplainLoadFilteredPolicy(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := casbun.NewAdapter(context.Background(), initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	query, err := adapter.ExplainLoadFilteredPolicy(casbun.Filter{PType: []string{"p"}, V0: []string{"alice", "bob"}})
+	if err != nil {
+		t.Fatalf("unable to explain load: %v", err)
+	}
+	for _, want := range []string{`SELECT`, `"ptype" IN ('p')`, `"v0" IN ('alice', 'bob')`} {
+		if !strings.Contains(query, want) {
+			t.Errorf("got query %q, want it to contain %q", query, want)
+		}
+	}
+}
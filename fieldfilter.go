@@ -0,0 +1,96 @@
+package casbun
+
+import (
+	"context"
+	"fmt"
+)
+
+// FieldFilter matches rules on the values of their fields by position, as an
+// alternative to the fieldIndex and fieldValues of the filtered operations of
+// Casbin, which are prone to off-by-one mistakes. A field left unset matches
+// any value. The zero FieldFilter matches every rule.
+//
+// Example:
+//
+//	// Matches the rules of alice on any object with the read action.
+//	f := casbun.FieldFilter{}.Sub("alice").Act("read")
+type FieldFilter struct {
+	values [6]string
+	err    error
+}
+
+// Sub returns a copy of f matching the subject, the first field of the rule.
+func (f FieldFilter) Sub(value string) FieldFilter {
+	return f.At(0, value)
+}
+
+// Obj returns a copy of f matching the object, the second field of the rule.
+func (f FieldFilter) Obj(value string) FieldFilter {
+	return f.At(1, value)
+}
+
+// Act returns a copy of f matching the action, the third field of the rule.
+func (f FieldFilter) Act(value string) FieldFilter {
+	return f.At(2, value)
+}
+
+// At returns a copy of f matching value at the field index, between 0 and 5.
+// An empty value matches any value. An index out of range makes the filter
+// fail with an error wrapping ErrInvalidFieldIndex when used.
+func (f FieldFilter) At(index int, value string) FieldFilter {
+	if index < 0 || index >= len(f.values) {
+		if f.err == nil {
+			f.err = fmt.Errorf("%w: field index %d is out of range [0, 5]", ErrInvalidFieldIndex, index)
+		}
+		return f
+	}
+	f.values[index] = value
+	return f
+}
+
+// Fields returns the field index and field values matching the rules matched
+// by f, for the filtered operations of Casbin. The unset fields between the
+// set ones are returned as empty values, which match any value.
+//
+// Example:
+//
+//	fieldIndex, fieldValues, err := casbun.FieldFilter{}.Obj("data1").Fields()
+//	if err != nil {
+//	    return err
+//	}
+//	rules, err := adapter.GetFilteredPolicy(ctx, "p", fieldIndex, fieldValues...)
+func (f FieldFilter) Fields() (fieldIndex int, fieldValues []string, err error) {
+	if f.err != nil {
+		return 0, nil, f.err
+	}
+
+	first, last := -1, -1
+	for i, value := range f.values {
+		if value == "" {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	if first < 0 {
+		return 0, nil, nil
+	}
+	return first, append([]string(nil), f.values[first:last+1]...), nil
+}
+
+// RemoveByFilter removes the rules of ptype matched by f, like
+// RemoveFilteredPolicyCtx. The zero FieldFilter removes every rule of ptype.
+//
+// Example:
+//
+//	err := adapter.RemoveByFilter(ctx, "p", casbun.FieldFilter{}.Sub("alice").Act("write"))
+func (a *Adapter) RemoveByFilter(ctx context.Context, ptype string, f FieldFilter) (err error) {
+	defer wrapOpError("remove_by_filter", ptype, &err)
+	fieldIndex, fieldValues, err := f.Fields()
+	if err != nil {
+		return err
+	}
+	return a.RemoveFilteredPolicyCtx(ctx, "", ptype, fieldIndex, fieldValues...)
+}
@@ -0,0 +1,83 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestFieldFilterFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		filter     casbun.FieldFilter
+		wantIndex  int
+		wantValues []string
+		wantErr    error
+	}{
+		{name: "empty", filter: casbun.FieldFilter{}, wantIndex: 0},
+		{name: "subject", filter: casbun.FieldFilter{}.Sub("alice"), wantIndex: 0, wantValues: []string{"alice"}},
+		{name: "object", filter: casbun.FieldFilter{}.Obj("data1"), wantIndex: 1, wantValues: []string{"data1"}},
+		{
+			name:       "gap",
+			filter:     casbun.FieldFilter{}.Sub("alice").Act("read"),
+			wantIndex:  0,
+			wantValues: []string{"alice", "", "read"},
+		},
+		{name: "positional", filter: casbun.FieldFilter{}.At(4, "deny"), wantIndex: 4, wantValues: []string{"deny"}},
+		{name: "out of range", filter: casbun.FieldFilter{}.At(6, "x"), wantErr: casbun.ErrInvalidFieldIndex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			index, values, err := tt.filter.Fields()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if index != tt.wantIndex || !slices.Equal(values, tt.wantValues) {
+				t.Errorf("got fields %d %q, want %d %q", index, values, tt.wantIndex, tt.wantValues)
+			}
+		})
+	}
+}
+
+func TestRemoveByFilter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "read"},
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	// v0 and v2 are set while v1 is skipped, so any object matches.
+	if err := adapter.RemoveByFilter(ctx, "p", casbun.FieldFilter{}.Sub("alice").Act("read")); err != nil {
+		t.Fatalf("failed to remove policies: %v", err)
+	}
+
+	rules, err := adapter.LoadPolicyArray(ctx)
+	if err != nil {
+		t.Fatalf("failed to load policies: %v", err)
+	}
+	if want := [][]string{{"alice", "data1", "write"}, {"bob", "data1", "read"}}; !util.Array2DEquals(rules["p"], want) {
+		t.Errorf("got rules %v, want %v", rules["p"], want)
+	}
+
+	if err := adapter.RemoveByFilter(ctx, "p", casbun.FieldFilter{}.At(-1, "alice")); !errors.Is(err, casbun.ErrInvalidFieldIndex) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrInvalidFieldIndex)
+	}
+}
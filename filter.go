@@ -0,0 +1,438 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/uptrace/bun"
+)
+
+// Filter defines the policy rules loaded by LoadFilteredPolicy.
+// Each non-empty field restricts the matching column to one of the listed
+// values, while empty fields match every value.
+//
+// The prefix fields restrict the matching column to values starting with the
+// prefix, which suits hierarchical naming conventions such as "role:eng:".
+// Note that SQLite compares ASCII letters case-insensitively in prefix matches.
+//
+// Domain restricts the rules of each ptype listed in DomainIndex to those
+// storing Domain at the given value index. Rules of other ptypes are loaded
+// regardless of their domain. FilterByDomain builds such a filter.
+//
+// Example:
+//
+//	// Load the policies of alice and bob only.
+//	err := enforcer.LoadFilteredPolicy(casbun.Filter{
+//	    PType: []string{"p"},
+//	    V0:    []string{"alice", "bob"},
+//	})
+type Filter struct {
+	PType []string
+	V0    []string
+	V1    []string
+	V2    []string
+	V3    []string
+	V4    []string
+	V5    []string
+
+	V0Prefix string
+	V1Prefix string
+	V2Prefix string
+	V3Prefix string
+	V4Prefix string
+	V5Prefix string
+
+	Domain      string
+	DomainIndex map[string]int
+}
+
+// WithCaseInsensitive matches the values of filters regardless of their case,
+// for identity providers emitting subjects with inconsistent casing such as
+// "Alice" and "alice". It applies to LoadFilteredPolicy and to the filtered
+// operations such as RemoveFilteredPolicy, which compare LOWER(vN) with
+// LOWER(value) and so can not use the indexes on the value columns.
+//
+// The unique index is not affected: "Alice" and "alice" remain distinct rules.
+// Note that SQLite only lowers ASCII letters.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithCaseInsensitive())
+func WithCaseInsensitive() CasbinBunOption {
+	return func(a *Adapter) {
+		a.caseInsensitive = true
+	}
+}
+
+// FilterByDomain returns a filter loading only the rules of domain, for RBAC
+// with domains models. The domainIndex maps each ptype to the position of the
+// domain in its rules, as the position differs between sections.
+//
+// Example:
+//
+//	// g = _, _, dom
+//	// p = sub, dom, obj, act
+//	filter, err := casbun.FilterByDomain("domain1", map[string]int{"g": 2, "p": 1})
+//	if err != nil {
+//	    return err
+//	}
+//	err = enforcer.LoadFilteredPolicy(filter)
+func FilterByDomain(domain string, domainIndex map[string]int) (Filter, error) {
+	if len(domainIndex) == 0 {
+		return Filter{}, errors.New("casbun: domain index must map at least one ptype")
+	}
+
+	f := Filter{Domain: domain, DomainIndex: domainIndex}
+	if err := f.validate(); err != nil {
+		return Filter{}, err
+	}
+	return f, nil
+}
+
+// validate reports the settings of the filter that can not be turned into a
+// query.
+func (f Filter) validate() error {
+	for ptype, index := range f.DomainIndex {
+		if index < 0 || index > 5 {
+			return fmt.Errorf("casbun: domain index %d of ptype %q is out of range [0, 5]", index, ptype)
+		}
+	}
+	return nil
+}
+
+func (f Filter) values() [][]string {
+	return [][]string{f.V0, f.V1, f.V2, f.V3, f.V4, f.V5}
+}
+
+func (f Filter) prefixes() []string {
+	return []string{f.V0Prefix, f.V1Prefix, f.V2Prefix, f.V3Prefix, f.V4Prefix, f.V5Prefix}
+}
+
+// apply adds the filter conditions to query. When caseInsensitive is set, the
+// values are compared with the column values regardless of their case.
+func (f Filter) apply(query *bun.SelectQuery, caseInsensitive bool) *bun.SelectQuery {
+	if len(f.PType) > 0 {
+		query = query.Where("? IN (?)", ptypeColumn, bun.In(f.PType))
+	}
+
+	for i, values := range f.values() {
+		if len(values) == 0 {
+			continue
+		}
+		col := valueColumn(i)
+		if caseInsensitive {
+			args := make([]interface{}, 0, len(values)+1)
+			args = append(args, col)
+			for _, value := range values {
+				args = append(args, value)
+			}
+			placeholders := strings.TrimSuffix(strings.Repeat("LOWER(?), ", len(values)), ", ")
+			query = query.Where("LOWER(?) IN ("+placeholders+")", args...)
+		} else {
+			query = query.Where("? IN (?)", col, bun.In(values))
+		}
+	}
+
+	for i, prefix := range f.prefixes() {
+		if prefix != "" {
+			col := valueColumn(i)
+			if caseInsensitive {
+				query = query.Where("LOWER(?) LIKE LOWER(?) ESCAPE '!'", col, likePrefix(prefix))
+			} else {
+				query = query.Where("? LIKE ? ESCAPE '!'", col, likePrefix(prefix))
+			}
+		}
+	}
+
+	if len(f.DomainIndex) > 0 {
+		ptypes := make([]string, 0, len(f.DomainIndex))
+		for ptype := range f.DomainIndex {
+			ptypes = append(ptypes, ptype)
+		}
+		sort.Strings(ptypes)
+
+		query = query.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			for _, ptype := range ptypes {
+				q = q.WhereOr(
+					"? = ? AND "+equalCondition(caseInsensitive),
+					ptypeColumn,
+					ptype,
+					valueColumn(f.DomainIndex[ptype]),
+					f.Domain,
+				)
+			}
+			return q.WhereOr("? NOT IN (?)", ptypeColumn, bun.In(ptypes))
+		})
+	}
+
+	return query
+}
+
+// equalCondition returns the condition comparing a column with a single
+// value, regardless of case when caseInsensitive is set. It takes the column
+// identifier, then the value as arguments.
+func equalCondition(caseInsensitive bool) string {
+	if caseInsensitive {
+		return "LOWER(?) = LOWER(?)"
+	}
+	return "? = ?"
+}
+
+// likePrefix returns a LIKE pattern matching values starting with prefix.
+// The wildcards of prefix are escaped with '!', which unlike a backslash needs
+// no escaping in the string literals of any dialect.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+}
+
+// LoadFilteredPolicy loads only policy rules that match the filter.
+// The filter must be a Filter or a *Filter. A nil filter, untyped or a nil
+// *Filter, loads all policy rules.
+func (a *Adapter) LoadFilteredPolicy(model model.Model, filter interface{}) error {
+	ctx, cancel := a.operationContext("load_filtered_policy")
+	defer cancel()
+	return a.LoadFilteredPolicyCtx(ctx, model, filter)
+}
+
+// LoadFilteredPolicyCtx loads only policy rules that match the filter with context.
+// The filter must be a Filter or a *Filter. A nil filter, untyped or a nil
+// *Filter, loads all policy rules.
+func (a *Adapter) LoadFilteredPolicyCtx(
+	ctx context.Context,
+	model model.Model,
+	filter interface{},
+) (err error) {
+	defer wrapOpError("load_filtered_policy", "", &err)
+	defer a.observe("load_filtered_policy")()
+
+	if isNilFilter(filter) {
+		return a.LoadPolicyCtx(ctx, model)
+	}
+
+	f, err := toFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	if err := a.loadPolicy(ctx, model, f); err != nil {
+		return err
+	}
+	if err := a.runAfterLoad(model); err != nil {
+		return err
+	}
+
+	a.filtered = true
+	return nil
+}
+
+// LoadIncrementalFilteredPolicy adds the policy rules that match the filter to
+// model, keeping the rules it already holds. This lets partitioned policies be
+// loaded lazily, one filter at a time, into the same enforcer. Rules already
+// present in model are not added twice.
+// The filter must be a Filter or a *Filter. A nil filter, untyped or a nil
+// *Filter, adds all policy rules.
+//
+// Example:
+//
+//	filter, err := casbun.FilterByDomain("domain2", map[string]int{"g": 2, "p": 1})
+//	if err != nil {
+//	    return err
+//	}
+//	if err := adapter.LoadIncrementalFilteredPolicy(enforcer.GetModel(), filter); err != nil {
+//	    return err
+//	}
+//	err = enforcer.BuildRoleLinks()
+func (a *Adapter) LoadIncrementalFilteredPolicy(model model.Model, filter interface{}) error {
+	ctx, cancel := a.operationContext("load_incremental_filtered_policy")
+	defer cancel()
+	return a.LoadIncrementalFilteredPolicyCtx(ctx, model, filter)
+}
+
+// LoadIncrementalFilteredPolicyCtx adds the policy rules that match the filter
+// to model with context, keeping the rules it already holds.
+// The filter must be a Filter or a *Filter. A nil filter, untyped or a nil
+// *Filter, adds all policy rules.
+func (a *Adapter) LoadIncrementalFilteredPolicyCtx(
+	ctx context.Context,
+	model model.Model,
+	filter interface{},
+) (err error) {
+	defer wrapOpError("load_incremental_filtered_policy", "", &err)
+	defer a.observe("load_incremental_filtered_policy")()
+
+	var f Filter
+	if !isNilFilter(filter) {
+		var err error
+		if f, err = toFilter(filter); err != nil {
+			return err
+		}
+	}
+
+	if err := a.loadPolicy(ctx, model, f); err != nil {
+		return err
+	}
+	if err := a.runAfterLoad(model); err != nil {
+		return err
+	}
+
+	// A nil filter leaves the model holding every stored rule.
+	a.filtered = !isNilFilter(filter)
+	return nil
+}
+
+// LoadPolicyWithQuery loads the policy rules selected by the base select
+// query of the adapter as modified by apply, which may add any WHERE, ORDER or
+// LIMIT clause. It is an escape hatch for the filters Filter can not express.
+// The policy table is aliased as cp, and the rows are scanned into
+// CasbinPolicy, so apply must not change the selected columns.
+//
+// Example:
+//
+//	err := adapter.LoadPolicyWithQuery(ctx, enforcer.GetModel(), func(q *bun.SelectQuery) *bun.SelectQuery {
+//	    return q.Where("v1 LIKE ?", "data%").Order("id")
+//	})
+func (a *Adapter) LoadPolicyWithQuery(
+	ctx context.Context,
+	model model.Model,
+	apply func(q *bun.SelectQuery) *bun.SelectQuery,
+) (err error) {
+	defer wrapOpError("load_policy_with_query", "", &err)
+	defer a.observe("load_policy_with_query")()
+
+	if err := a.loadPolicyWithQuery(ctx, model, apply); err != nil {
+		return err
+	}
+	if err := a.runAfterLoad(model); err != nil {
+		return err
+	}
+
+	a.filtered = true
+	return nil
+}
+
+// SelectPolicies returns the stored rows matching filter as CasbinPolicy
+// values, including their ids, for admin tooling that needs the surrogate key
+// of a rule, e.g. to build edit links. The rows are ordered like the loaded
+// rules, including those of the tables set by WithPTypeTable. The ids are
+// left zero when WithCompositeKey or WithUUIDKey is used.
+//
+// Example:
+//
+//	policies, err := adapter.SelectPolicies(ctx, casbun.Filter{PType: []string{"p"}, V0: []string{"alice"}})
+//	if err != nil {
+//	    return err
+//	}
+//	for _, policy := range policies {
+//	    fmt.Printf("/admin/policies/%d\n", policy.ID)
+//	}
+func (a *Adapter) SelectPolicies(ctx context.Context, filter Filter) (_ []CasbinPolicy, err error) {
+	defer wrapOpError("select_policies", "", &err)
+	defer a.observe("select_policies")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+
+	out := make([]CasbinPolicy, 0)
+	for _, r := range a.routes() {
+		var policies []CasbinPolicy
+		if err := r.orderedSelect(filter.apply(r.newSelect(ctx, r.reader(ctx), &policies), r.caseInsensitive)).
+			ApplyQueryBuilder(r.allowedPTypes).
+			Scan(ctx); err != nil {
+			return nil, tableError(err)
+		}
+		out = append(out, policies...)
+	}
+	return out, nil
+}
+
+// PolicyWithID is a stored rule along with the id of the row storing it, as
+// returned by LoadPolicyWithIDs.
+type PolicyWithID struct {
+	ID    int64
+	PType string
+	Rule  []string
+}
+
+// LoadPolicyWithIDs returns every stored rule along with the id of its row,
+// for sync pipelines keying their downstream updates on it, which the model
+// filled by LoadPolicy has no place for. The rules are returned in the order
+// LoadPolicy loads them, including those of the tables set by
+// WithPTypeTable. The ids are left zero when WithCompositeKey, WithUUIDKey or
+// WithModel is used.
+//
+// Example:
+//
+//	policies, err := adapter.LoadPolicyWithIDs(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, policy := range policies {
+//	    sink.Upsert(policy.ID, policy.PType, policy.Rule)
+//	}
+func (a *Adapter) LoadPolicyWithIDs(ctx context.Context) (_ []PolicyWithID, err error) {
+	defer wrapOpError("load_policy_with_ids", "", &err)
+	defer a.observe("load_policy_with_ids")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	out := make([]PolicyWithID, 0)
+	for _, r := range a.routes() {
+		policies, err := r.selectLoadedPolicies(ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			out = append(out, PolicyWithID{ID: policy.ID, PType: policy.PType, Rule: policy.filterValues()})
+		}
+	}
+	return out, nil
+}
+
+// isNilFilter reports whether the filter passed to LoadFilteredPolicy is nil,
+// either untyped or a nil *Filter.
+func isNilFilter(filter interface{}) bool {
+	f, ok := filter.(*Filter)
+	return filter == nil || ok && f == nil
+}
+
+// toFilter returns the validated Filter passed to LoadFilteredPolicy, which
+// must not be nil.
+func toFilter(filter interface{}) (Filter, error) {
+	var f Filter
+	switch filter := filter.(type) {
+	case Filter:
+		f = filter
+	case *Filter:
+		f = *filter
+	default:
+		return Filter{}, fmt.Errorf("casbun: unsupported filter type %T", filter)
+	}
+
+	if err := f.validate(); err != nil {
+		return Filter{}, err
+	}
+	return f, nil
+}
+
+// IsFiltered returns true if the loaded policy has been filtered.
+func (a *Adapter) IsFiltered() bool {
+	return a.filtered
+}
+
+// IsFilteredCtx returns true if the loaded policy has been filtered.
+func (a *Adapter) IsFilteredCtx(context.Context) bool {
+	return a.filtered
+}
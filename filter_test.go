@@ -0,0 +1,482 @@
+package casbun_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+)
+
+var domainModelStr = `
+    [request_definition]
+    r = sub, dom, obj, act
+
+    [policy_definition]
+    p = sub, dom, obj, act
+
+    [role_definition]
+    g = _, _, _
+
+    [policy_effect]
+    e = some(where (p.eft == allow))
+
+    [matchers]
+    m = g(r.sub, p.sub, r.dom) && r.dom == p.dom && r.obj == p.obj && r.act == p.act
+`
+
+func TestLoadFilteredPolicyByDomain(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicies("g", "g", [][]string{
+		{"alice", "admin", "domain1"},
+		{"bob", "admin", "domain2"},
+		{"carol", "reader", "domain1"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"admin", "domain1", "data1", "write"},
+		{"admin", "domain2", "data2", "write"},
+		// The object matches the domain name, which must not match the
+		// domain index of g rules.
+		{"reader", "domain2", "domain1", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(domainModelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	filter, err := casbun.FilterByDomain("domain1", map[string]int{"g": 2, "p": 1})
+	if err != nil {
+		t.Fatalf("unable to build filter: %v", err)
+	}
+	if err := e.LoadFilteredPolicy(filter); err != nil {
+		t.Fatalf("unable to load filtered policy: %v", err)
+	}
+
+	if !adapter.IsFiltered() {
+		t.Errorf("adapter should report a filtered policy")
+	}
+
+	gotGrouping, err := e.GetGroupingPolicy()
+	if err != nil {
+		t.Fatalf("unable to get grouping policy: %v", err)
+	}
+	wantGrouping := [][]string{
+		{"alice", "admin", "domain1"},
+		{"carol", "reader", "domain1"},
+	}
+	if !util.SortedArray2DEquals(wantGrouping, gotGrouping) {
+		t.Errorf("got grouping policy %v, want %v", gotGrouping, wantGrouping)
+	}
+
+	gotPolicy, err := e.GetPolicy()
+	if err != nil {
+		t.Fatalf("unable to get policy: %v", err)
+	}
+	wantPolicy := [][]string{{"admin", "domain1", "data1", "write"}}
+	if !util.SortedArray2DEquals(wantPolicy, gotPolicy) {
+		t.Errorf("got policy %v, want %v", gotPolicy, wantPolicy)
+	}
+
+	if _, err := casbun.FilterByDomain("domain1", map[string]int{"g": 6}); err == nil {
+		t.Errorf("expected an error for an out of range domain index")
+	}
+	if _, err := casbun.FilterByDomain("domain1", nil); err == nil {
+		t.Errorf("expected an error for a missing domain index")
+	}
+}
+
+func TestLoadFilteredPolicyByPrefix(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"role:eng:backend", "data1", "read"},
+		{"role:eng:frontend", "data2", "read"},
+		{"role:ops", "data1", "write"},
+		{"team_a:alice", "data1", "read"},
+		{"teamXa:bob", "data1", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter casbun.Filter
+		want   [][]string
+	}{
+		{
+			name:   "prefix",
+			filter: casbun.Filter{V0Prefix: "role:eng:"},
+			want: [][]string{
+				{"role:eng:backend", "data1", "read"},
+				{"role:eng:frontend", "data2", "read"},
+			},
+		},
+		{
+			name:   "prefix with underscore",
+			filter: casbun.Filter{V0Prefix: "team_"},
+			want:   [][]string{{"team_a:alice", "data1", "read"}},
+		},
+		{
+			name:   "prefix combined with values",
+			filter: casbun.Filter{V0Prefix: "role:", V1: []string{"data1"}},
+			want: [][]string{
+				{"role:eng:backend", "data1", "read"},
+				{"role:ops", "data1", "write"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := model.NewModelFromString(modelStr)
+			if err := adapter.LoadFilteredPolicy(m, tt.filter); err != nil {
+				t.Fatalf("unable to load filtered policy: %v", err)
+			}
+
+			got, _ := m.GetPolicy("p", "p")
+			if !util.SortedArray2DEquals(tt.want, got) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadIncrementalFilteredPolicy(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicies("g", "g", [][]string{
+		{"alice", "admin", "domain1"},
+		{"bob", "admin", "domain2"},
+		{"carol", "admin", "domain3"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"admin", "domain1", "data1", "write"},
+		{"admin", "domain2", "data2", "write"},
+		{"admin", "domain3", "data3", "write"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(domainModelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	domainIndex := map[string]int{"g": 2, "p": 1}
+	filter, err := casbun.FilterByDomain("domain1", domainIndex)
+	if err != nil {
+		t.Fatalf("unable to build filter: %v", err)
+	}
+	if err := e.LoadFilteredPolicy(filter); err != nil {
+		t.Fatalf("unable to load filtered policy: %v", err)
+	}
+
+	filter, err = casbun.FilterByDomain("domain2", domainIndex)
+	if err != nil {
+		t.Fatalf("unable to build filter: %v", err)
+	}
+	if err := adapter.LoadIncrementalFilteredPolicy(e.GetModel(), filter); err != nil {
+		t.Fatalf("unable to load incremental filtered policy: %v", err)
+	}
+	if err := e.BuildRoleLinks(); err != nil {
+		t.Fatalf("unable to build role links: %v", err)
+	}
+
+	if !adapter.IsFiltered() {
+		t.Errorf("adapter should report a filtered policy")
+	}
+
+	gotGrouping, err := e.GetGroupingPolicy()
+	if err != nil {
+		t.Fatalf("unable to get grouping policy: %v", err)
+	}
+	wantGrouping := [][]string{
+		{"alice", "admin", "domain1"},
+		{"bob", "admin", "domain2"},
+	}
+	if !util.SortedArray2DEquals(wantGrouping, gotGrouping) {
+		t.Errorf("got grouping policy %v, want %v", gotGrouping, wantGrouping)
+	}
+
+	gotPolicy, err := e.GetPolicy()
+	if err != nil {
+		t.Fatalf("unable to get policy: %v", err)
+	}
+	wantPolicy := [][]string{
+		{"admin", "domain1", "data1", "write"},
+		{"admin", "domain2", "data2", "write"},
+	}
+	if !util.SortedArray2DEquals(wantPolicy, gotPolicy) {
+		t.Errorf("got policy %v, want %v", gotPolicy, wantPolicy)
+	}
+
+	if ok, _ := e.Enforce("bob", "domain2", "data2", "write"); !ok {
+		t.Errorf("bob should be allowed to write data2 in domain2")
+	}
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		opts          []casbun.CasbinBunOption
+		wantLoaded    int
+		wantRemaining int
+	}{
+		{
+			name:          "case sensitive",
+			wantRemaining: 3,
+		},
+		{
+			name:          "case insensitive",
+			opts:          []casbun.CasbinBunOption{casbun.WithCaseInsensitive()},
+			wantLoaded:    2,
+			wantRemaining: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			adapter, err := casbun.NewAdapter(ctx, initDB(), tt.opts...)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			// The unique index keeps telling the casings apart.
+			if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{
+				{"alice", "data1", "read"},
+				{"ALICE", "data1", "read"},
+				{"bob", "data1", "read"},
+			}); err != nil {
+				t.Fatalf("failed to add policies: %v", err)
+			}
+
+			m, _ := model.NewModelFromString(modelStr)
+			if err := adapter.LoadFilteredPolicyCtx(ctx, m, casbun.Filter{V0: []string{"Alice"}}); err != nil {
+				t.Fatalf("unable to load filtered policy: %v", err)
+			}
+			if got := len(m["p"]["p"].Policy); got != tt.wantLoaded {
+				t.Errorf("got %d loaded rules, want %d", got, tt.wantLoaded)
+			}
+
+			m, _ = model.NewModelFromString(modelStr)
+			if err := adapter.LoadFilteredPolicyCtx(ctx, m, casbun.Filter{V0Prefix: "Ali"}); err != nil {
+				t.Fatalf("unable to load filtered policy: %v", err)
+			}
+			// SQLite matches prefixes case-insensitively either way.
+			if got := len(m["p"]["p"].Policy); got != 2 {
+				t.Errorf("got %d rules loaded by prefix, want 2", got)
+			}
+
+			if err := adapter.RemoveFilteredPolicyCtx(ctx, "p", "p", 0, "Alice", "DATA1"); err != nil {
+				t.Fatalf("unable to remove filtered policy: %v", err)
+			}
+			remaining, err := adapter.GetFilteredPolicy(ctx, "p", 0)
+			if err != nil {
+				t.Fatalf("unable to get policies: %v", err)
+			}
+			if len(remaining) != tt.wantRemaining {
+				t.Errorf("got remaining policies %v, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestLoadPolicyWithQuery(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "files", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyWithQuery(ctx, m, func(q *bun.SelectQuery) *bun.SelectQuery {
+		return q.Where("v1 LIKE 'data%'")
+	}); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	want := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+	}
+	if got := m["p"]["p"].Policy; !util.SortedArray2DEquals(want, got) {
+		t.Errorf("got policy %v, want %v", got, want)
+	}
+	if !adapter.IsFiltered() {
+		t.Errorf("adapter should report a filtered policy")
+	}
+}
+
+func TestSelectPolicies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rules := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}, {"alice", "data2", "read"}}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", rules); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	got, err := adapter.SelectPolicies(ctx, casbun.Filter{PType: []string{"p"}, V0: []string{"alice"}})
+	if err != nil {
+		t.Fatalf("unable to select policies: %v", err)
+	}
+	want := []casbun.CasbinPolicy{
+		{ID: 1, PType: "p", V0: "alice", V1: "data1", V2: "read"},
+		{ID: 3, PType: "p", V0: "alice", V1: "data2", V2: "read"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d policies, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got policy %+v, want %+v", got[i], want[i])
+		}
+	}
+}
+
+func TestSelectPoliciesPTypeTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithPTypeTable(map[string]string{"g": "casbin_groups"}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	got, err := adapter.SelectPolicies(ctx, casbun.Filter{V0: []string{"alice"}})
+	if err != nil {
+		t.Fatalf("unable to select policies: %v", err)
+	}
+	want := []casbun.CasbinPolicy{
+		{ID: 1, PType: "p", V0: "alice", V1: "data1", V2: "read"},
+		{ID: 1, PType: "g", V0: "alice", V1: "admin"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d policies, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got policy %+v, want %+v", got[i], want[i])
+		}
+	}
+}
+
+func TestLoadPolicyWithIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	got, err := adapter.LoadPolicyWithIDs(ctx)
+	if err != nil {
+		t.Fatalf("unable to load policy with ids: %v", err)
+	}
+	want := []casbun.PolicyWithID{
+		{ID: 1, PType: "p", Rule: []string{"alice", "data1", "read"}},
+		{ID: 2, PType: "p", Rule: []string{"bob", "data2", "write"}},
+		{ID: 3, PType: "g", Rule: []string{"alice", "admin"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadFilteredPolicyNilFilter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	for name, load := range map[string]func(model.Model, interface{}) error{
+		"filtered":    adapter.LoadFilteredPolicy,
+		"incremental": adapter.LoadIncrementalFilteredPolicy,
+	} {
+		m, _ := model.NewModelFromString(modelStr)
+		if err := load(m, (*casbun.Filter)(nil)); err != nil {
+			t.Fatalf("%s: unable to load policy with a nil *Filter: %v", name, err)
+		}
+		if got, _ := m.GetPolicy("p", "p"); len(got) != 2 {
+			t.Errorf("%s: got policies %v, want every stored rule", name, got)
+		}
+		if adapter.IsFiltered() {
+			t.Errorf("%s: a load with a nil *Filter should not be filtered", name)
+		}
+	}
+
+	if _, err := adapter.ExplainLoadFilteredPolicy((*casbun.Filter)(nil)); err != nil {
+		t.Errorf("unable to explain a load with a nil *Filter: %v", err)
+	}
+}
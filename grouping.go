@@ -0,0 +1,22 @@
+package casbun
+
+import "context"
+
+// AddGroupingPolicies grants roles through the two-column grouping rules of
+// gtype, such as "g", each pair holding a user and a role. The pairs already
+// stored are skipped like in AddPoliciesIgnoreExisting, and the number of
+// pairs added is returned.
+//
+// Example:
+//
+//	added, err := adapter.AddGroupingPolicies(ctx, "g", [][2]string{
+//	    {"alice", "editor"},
+//	    {"alice", "viewer"},
+//	})
+func (a *Adapter) AddGroupingPolicies(ctx context.Context, gtype string, pairs [][2]string) (int64, error) {
+	rules := make([][]string, 0, len(pairs))
+	for _, pair := range pairs {
+		rules = append(rules, []string{pair[0], pair[1]})
+	}
+	return a.AddPoliciesIgnoreExisting(ctx, gtype, rules)
+}
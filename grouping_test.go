@@ -0,0 +1,50 @@
+package casbun_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestAddGroupingPolicies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"user0", "role0"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	pairs := make([][2]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		pairs = append(pairs, [2]string{fmt.Sprintf("user%d", i), fmt.Sprintf("role%d", i%5)})
+	}
+	added, err := adapter.AddGroupingPolicies(ctx, "g", pairs)
+	if err != nil {
+		t.Fatalf("failed to add grouping policies: %v", err)
+	}
+	// The first pair was already stored.
+	if added != 49 {
+		t.Errorf("got %d added pairs, want 49", added)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	for _, pair := range pairs {
+		if ok, _ := m.HasPolicy("g", "g", pair[:]); !ok {
+			t.Errorf("grouping policy %v was not stored", pair)
+		}
+	}
+	if got, _ := m.GetPolicy("g", "g"); len(got) != 50 {
+		t.Errorf("got %d grouping policies, want 50", len(got))
+	}
+}
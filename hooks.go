@@ -0,0 +1,109 @@
+package casbun
+
+import (
+	"context"
+
+	"github.com/casbin/casbin/v2/model"
+)
+
+// MutationHook is called by the adapter around the operations adding,
+// removing or updating rules, see WithBeforeMutation. op is the name of the
+// EventOp of the operation, such as "add", and rule is one of the rules it
+// changes: the new rules of an update, and for the removal of filtered rules
+// the filter, with empty values for the fields it does not constrain.
+type MutationHook func(ctx context.Context, op, ptype string, rule []string) error
+
+// WithBeforeMutation calls hook for each rule before it is added, removed or
+// updated, to enforce business rules at the adapter boundary. The operation
+// is aborted without writing anything if hook returns an error, which is
+// returned to the caller. SavePolicy and Clear, which replace the whole
+// policy, do not call hook.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithBeforeMutation(
+//	    func(ctx context.Context, op, ptype string, rule []string) error {
+//	        if op == "add" && ptype == "g" && slices.Contains(rule, "admin") {
+//	            return errors.New("admin can not be granted through the policy API")
+//	        }
+//	        return nil
+//	    },
+//	))
+func WithBeforeMutation(hook MutationHook) CasbinBunOption {
+	return func(a *Adapter) {
+		a.beforeMutation = hook
+	}
+}
+
+// WithAfterMutation calls hook for each rule once it has been added, removed
+// or updated, like the hook set by WithBeforeMutation. An error returned by
+// hook is returned to the caller, but the change is kept.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithAfterMutation(
+//	    func(ctx context.Context, op, ptype string, rule []string) error {
+//	        audit.Log(ctx, op, ptype, rule)
+//	        return nil
+//	    },
+//	))
+func WithAfterMutation(hook MutationHook) CasbinBunOption {
+	return func(a *Adapter) {
+		a.afterMutation = hook
+	}
+}
+
+// WithAfterLoad calls hook with the model once a load has added the stored
+// rules to it, to post-process the policy, e.g. expanding wildcard subjects or
+// adding computed rules. It is called by every load, such as LoadPolicy and
+// LoadFilteredPolicy, and the load fails with the error returned by hook.
+// Rules added by hook are not stored.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithAfterLoad(func(m model.Model) error {
+//	    return m.AddPolicy("p", "p", []string{"root", "*", "*"})
+//	}))
+func WithAfterLoad(hook func(model model.Model) error) CasbinBunOption {
+	return func(a *Adapter) {
+		a.afterLoad = hook
+	}
+}
+
+// runAfterLoad calls the hook set by WithAfterLoad with model.
+func (a *Adapter) runAfterLoad(model model.Model) error {
+	if a.afterLoad == nil {
+		return nil
+	}
+	return a.afterLoad(model)
+}
+
+// before calls the hook set by WithBeforeMutation for event.
+func (a *Adapter) before(ctx context.Context, event PolicyEvent) error {
+	return a.runHook(ctx, a.beforeMutation, event)
+}
+
+// runHook calls hook for each rule changed by event, and stops at the first
+// error.
+func (a *Adapter) runHook(ctx context.Context, hook MutationHook, event PolicyEvent) error {
+	if hook == nil {
+		return nil
+	}
+
+	rules := event.Rules
+	switch event.Op {
+	case EventAdd, EventRemove, EventUpdate:
+	case EventRemoveFiltered:
+		filter := make([]string, event.FieldIndex, event.FieldIndex+len(event.FieldValues))
+		rules = [][]string{append(filter, event.FieldValues...)}
+	default:
+		return nil
+	}
+
+	for _, rule := range rules {
+		if err := hook(ctx, event.Op.String(), event.PType, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestMutationHooks(t *testing.T) {
+	t.Parallel()
+
+	errAdmin := errors.New("admin can not be granted")
+	var after []string
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db,
+		casbun.WithBeforeMutation(func(ctx context.Context, op, ptype string, rule []string) error {
+			if op == "add" && slices.Contains(rule, "admin") {
+				return errAdmin
+			}
+			return nil
+		}),
+		casbun.WithAfterMutation(func(ctx context.Context, op, ptype string, rule []string) error {
+			after = append(after, op+" "+ptype+" "+strings.Join(rule, ","))
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); !errors.Is(err, errAdmin) {
+		t.Errorf("got error %v, want %v", err, errAdmin)
+	}
+	count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("got %d rules after the vetoed add, want 0", count)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "editor"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	if err := adapter.UpdatePolicyCtx(ctx, "g", "g", []string{"alice", "editor"}, []string{"alice", "viewer"}); err != nil {
+		t.Fatalf("failed to update grouping policy: %v", err)
+	}
+	if err := adapter.RemoveFilteredPolicyCtx(ctx, "g", "g", 1, "viewer"); err != nil {
+		t.Fatalf("failed to remove filtered grouping policy: %v", err)
+	}
+
+	want := []string{"add g alice,editor", "update g alice,viewer", "remove_filtered g ,viewer"}
+	if !slices.Equal(after, want) {
+		t.Errorf("got after hooks %q, want %q", after, want)
+	}
+}
+
+func TestAfterLoad(t *testing.T) {
+	t.Parallel()
+
+	errLoad := errors.New("load rejected")
+	var fail bool
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithAfterLoad(func(m model.Model) error {
+		if fail {
+			return errLoad
+		}
+		return m.AddPolicy("p", "p", []string{"root", "data1", "read"})
+	}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	for _, sub := range []string{"alice", "root"} {
+		if ok, err := e.Enforce(sub, "data1", "read"); err != nil || !ok {
+			t.Errorf("got %v and error %v enforcing %s, want true", ok, err, sub)
+		}
+	}
+
+	// The synthetic rule is not stored.
+	rules, err := adapter.GetFilteredPolicy(ctx, "p", 0, "root")
+	if err != nil {
+		t.Fatalf("unable to get filtered policy: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Errorf("got stored rules %v, want none", rules)
+	}
+
+	fail = true
+	if err := e.LoadFilteredPolicy(casbun.Filter{V0: []string{"alice"}}); !errors.Is(err, errLoad) {
+		t.Errorf("got error %v, want %v", err, errLoad)
+	}
+}
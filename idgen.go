@@ -0,0 +1,67 @@
+package casbun
+
+import (
+	"errors"
+
+	"github.com/uptrace/bun"
+)
+
+// WithIDGenerator keys the policy table on an id returned by generate for
+// each inserted rule, instead of an autoincrement id, e.g. to use snowflake
+// ids, ULIDs or values of a sequence. As with WithUUIDKey, rules are still
+// matched by ptype and values, and CasbinPolicy.ID is left zero on rows read
+// back. It can not be combined with WithCompositeKey, and NewAdapter fails if
+// generate is nil.
+//
+// The id column is created as VARCHAR, so that it holds any generated value;
+// create the table beforehand to store the ids in another type, such as
+// BIGINT for snowflake ids. generate may be called concurrently.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithIDGenerator(func() interface{} {
+//	    return ulid.Make().String()
+//	}))
+func WithIDGenerator(generate func() interface{}) CasbinBunOption {
+	return func(a *Adapter) {
+		if generate == nil {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: id generator must not be nil"))
+			return
+		}
+		a.idGenerator = generate
+	}
+}
+
+// generatedIDCasbinPolicy is the row inserted by an adapter using
+// WithIDGenerator or WithUUIDKey.
+type generatedIDCasbinPolicy struct {
+	bun.BaseModel `bun:"casbin_policies,alias:cp"`
+	ID            interface{} `bun:"id,pk"`
+	PType         string      `bun:"ptype"`
+	V0            string      `bun:"v0"`
+	V1            string      `bun:"v1"`
+	V2            string      `bun:"v2"`
+	V3            string      `bun:"v3"`
+	V4            string      `bun:"v4"`
+	V5            string      `bun:"v5"`
+}
+
+// newGeneratedIDPolicies returns the rows inserting the policies held by
+// model, a *CasbinPolicy or a *[]CasbinPolicy, with a new generated id each.
+func (a *Adapter) newGeneratedIDPolicies(model interface{}) *[]generatedIDCasbinPolicy {
+	policies := policyRecords(model)
+	rows := make([]generatedIDCasbinPolicy, 0, len(policies))
+	for _, p := range policies {
+		rows = append(rows, generatedIDCasbinPolicy{
+			ID:    a.idGenerator(),
+			PType: p.PType,
+			V0:    p.V0,
+			V1:    p.V1,
+			V2:    p.V2,
+			V3:    p.V3,
+			V4:    p.V4,
+			V5:    p.V5,
+		})
+	}
+	return &rows
+}
@@ -0,0 +1,69 @@
+package casbun_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestIDGenerator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	var next atomic.Int64
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithIDGenerator(func() interface{} {
+		return fmt.Sprintf("rule-%d", next.Add(1))
+	}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	if _, err := e.AddPolicies([][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if _, err := e.AddPolicy("carol", "data2", "read"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	var ids []string
+	if err := db.NewRaw("SELECT id FROM casbin_policies ORDER BY id").Scan(ctx, &ids); err != nil {
+		t.Fatalf("unable to read ids: %v", err)
+	}
+	if want := []string{"rule-1", "rule-2", "rule-3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("got ids %v, want %v", ids, want)
+	}
+
+	if ok, err := e.RemovePolicy("bob", "data1", "read"); !ok || err != nil {
+		t.Fatalf("unable to remove policy: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{
+		{"alice", "data1", "write"},
+		{"carol", "data2", "read"},
+	})
+
+	if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithIDGenerator(nil)); err == nil {
+		t.Errorf("expected an error with a nil id generator")
+	}
+	if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithIDGenerator(func() interface{} { return 1 }), casbun.WithCompositeKey()); err == nil {
+		t.Errorf("expected an error when combining an id generator and a composite key")
+	}
+}
@@ -0,0 +1,55 @@
+package casbun
+
+import (
+	"context"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+	"github.com/uptrace/bun"
+)
+
+// ImportFromAdapter replaces the stored policy with the policy stored by src,
+// another Casbin adapter such as a file adapter, to migrate from another
+// storage backend. The rules of src are loaded into a copy of model, which
+// only provides the sections defining the ptypes, and saved like SavePolicy
+// in a single transaction, so the stored policy is kept if the import fails.
+// With SaveTruncate on MySQL, where TRUNCATE commits implicitly, use another
+// save strategy to keep the import transactional.
+//
+// Example:
+//
+//	src := fileadapter.NewAdapter("policy.csv")
+//	if err := adapter.ImportFromAdapter(ctx, src, enforcer.GetModel()); err != nil {
+//	    return err
+//	}
+func (a *Adapter) ImportFromAdapter(ctx context.Context, src persist.Adapter, model model.Model) (err error) {
+	defer wrapOpError("import_from_adapter", "", &err)
+	defer a.observe("import_from_adapter")()
+
+	imported := model.Copy()
+	imported.ClearPolicy()
+	if ctxSrc, ok := src.(persist.ContextAdapter); ok {
+		err = ctxSrc.LoadPolicyCtx(ctx, imported)
+	} else {
+		err = src.LoadPolicy(imported)
+	}
+	if err != nil {
+		return err
+	}
+
+	policies := a.allowedPolicies(modelPolicies(imported))
+	if len(policies) == 0 && a.refuseEmptySave {
+		return ErrEmptySave
+	}
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				return a.saveRoutedPolicyRecords(WithTx(ctx, tx), policies)
+			},
+		)
+	})
+	return a.notify(ctx, err, PolicyEvent{Op: EventSave})
+}
@@ -0,0 +1,52 @@
+package casbun_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	fileadapter "github.com/casbin/casbin/v2/persist/file-adapter"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestImportFromAdapter(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "policy.csv")
+	csv := "p, alice, data1, read\np, bob, data2, write\ng, alice, admin\n"
+	if err := os.WriteFile(path, []byte(csv), 0o600); err != nil {
+		t.Fatalf("failed to write policy file: %v", err)
+	}
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	// The stored rules are replaced by the imported ones.
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"carol", "data3", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.ImportFromAdapter(ctx, fileadapter.NewAdapter(path), m); err != nil {
+		t.Fatalf("failed to import policy: %v", err)
+	}
+	if len(m["p"]["p"].Policy) != 0 {
+		t.Errorf("got model rules %v, want the model left untouched", m["p"]["p"].Policy)
+	}
+
+	rules, err := adapter.LoadPolicyArray(ctx)
+	if err != nil {
+		t.Fatalf("failed to load policies: %v", err)
+	}
+	if want := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}; !util.Array2DEquals(rules["p"], want) {
+		t.Errorf("got p rules %v, want %v", rules["p"], want)
+	}
+	if want := [][]string{{"alice", "admin"}}; !util.Array2DEquals(rules["g"], want) {
+		t.Errorf("got g rules %v, want %v", rules["g"], want)
+	}
+}
@@ -0,0 +1,163 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+const (
+	// mysqlMaxIndexBytes is the maximum length of an InnoDB index key.
+	mysqlMaxIndexBytes = 3072
+	// mysqlMaxCharBytes is the number of bytes reserved for a utf8mb4 character.
+	mysqlMaxCharBytes = 4
+)
+
+// WithPtypeIndex sets whether the table is created with the secondary index on
+// the ptype column, which is the default. The index speeds up filtered loads
+// but slows down writes, so write-heavy deployments that rarely filter may
+// leave it out. The unique index on the rules is controlled by
+// WithUniqueIndex.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithPtypeIndex(false))
+func WithPtypeIndex(enabled bool) CasbinBunOption {
+	return func(a *Adapter) {
+		a.ptypeIndex = enabled
+	}
+}
+
+// WithUniqueIndex sets whether the table is created with the unique index on
+// the rules, which is the default. Leaving it out speeds up bulk imports of
+// raw data, after which EnsureUniqueIndex adds it. Without the index,
+// duplicate rules are not rejected. NewAdapter fails if the index is disabled
+// with WithCompositeKey, whose primary key enforces the uniqueness.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithUniqueIndex(false))
+//	// Import the policies, then:
+//	err = adapter.EnsureUniqueIndex(ctx)
+func WithUniqueIndex(enabled bool) CasbinBunOption {
+	return func(a *Adapter) {
+		a.noUniqueIndex = !enabled
+	}
+}
+
+// EnsureUniqueIndex creates the unique index on the rules unless it already
+// exists, typically after a bulk import into a table created without it by
+// WithUniqueIndex(false). It fails if the stored rules hold duplicates.
+func (a *Adapter) EnsureUniqueIndex(ctx context.Context) (err error) {
+	defer wrapOpError("ensure_unique_index", "", &err)
+	if a.compositeKey {
+		// The primary key already enforces the uniqueness.
+		return nil
+	}
+
+	return a.conn(ctx).RunInTx(ctx, a.txOptions(), a.createUniqueIndex)
+}
+
+// createUniqueIndex creates the unique index on the rules on tx unless it
+// already exists.
+func (a *Adapter) createUniqueIndex(ctx context.Context, tx bun.Tx) error {
+	uniqueIndex, _ := a.indexNames()
+	return a.createIndex(
+		ctx,
+		tx,
+		"CREATE UNIQUE INDEX",
+		uniqueIndex,
+		uniqueIndexColumns(tx.Dialect().Name(), a.columnLength, a.uniqueIndexedColumns()),
+	)
+}
+
+// createIndex creates the index name on columns of the policy table unless it
+// already exists. Dialects lacking CREATE INDEX IF NOT EXISTS report an
+// existing index as an error, which is ignored.
+func (a *Adapter) createIndex(
+	ctx context.Context,
+	tx bun.Tx,
+	statement, name, columns string,
+) error {
+	if dialectOf(tx.Dialect().Name()).indexIfNotExists {
+		statement += " IF NOT EXISTS"
+	}
+
+	_, err := tx.NewRaw(
+		statement+" ? ON ? ("+columns+")",
+		bun.Ident(name),
+		bun.Ident(a.tableName),
+	).Comment(a.queryComment(ctx)).Exec(ctx)
+	if err != nil && !isDuplicateIndex(err) {
+		return err
+	}
+
+	return nil
+}
+
+// WithUniqueColumns restricts the unique index to columns, a subset of ptype
+// and v0 to v5, for models whose rules are only identified by their first
+// values. Rules differing only in the other columns are then rejected as
+// duplicates. NewAdapter fails on an unknown or repeated column, and when
+// combined with WithCompositeKey, whose primary key spans every column.
+//
+// Example:
+//
+//	// Allow a single rule per subject, object and action.
+//	adapter, err := NewAdapter(ctx, db, WithUniqueColumns("ptype", "v0", "v1", "v2"))
+func WithUniqueColumns(columns ...string) CasbinBunOption {
+	return func(a *Adapter) {
+		if len(columns) == 0 {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: unique columns must not be empty"))
+			return
+		}
+
+		seen := make(map[string]bool, len(columns))
+		for _, col := range columns {
+			if !slices.Contains(a.Columns(), col) {
+				a.optionErr = errors.Join(a.optionErr, fmt.Errorf("casbun: unknown unique column %q", col))
+			} else if seen[col] {
+				a.optionErr = errors.Join(a.optionErr, fmt.Errorf("casbun: repeated unique column %q", col))
+			}
+			seen[col] = true
+		}
+		a.uniqueColumns = columns
+	}
+}
+
+// uniqueIndexedColumns returns the columns of the unique index, set by
+// WithUniqueColumns.
+func (a *Adapter) uniqueIndexedColumns() []string {
+	if a.uniqueColumns != nil {
+		return a.uniqueColumns
+	}
+	return a.Columns()
+}
+
+// uniqueIndexColumns returns the column list of the unique index on columns,
+// for columns of columnLength characters, or of unbounded length when
+// columnLength is 0.
+//
+// On MySQL the key of an InnoDB index is limited to 3072 bytes, and every
+// utf8mb4 character may take 4 bytes. When the columns do not fit, each
+// column is indexed on a prefix of equal length instead, so rules that differ
+// only after the prefix are then reported as duplicates.
+func uniqueIndexColumns(name dialect.Name, columnLength int, columns []string) string {
+	columns = slices.Clone(columns)
+
+	maxIndexBytes := dialectOf(name).maxIndexBytes
+	maxLength := maxIndexBytes / mysqlMaxCharBytes / len(columns)
+	if maxIndexBytes == 0 || (columnLength > 0 && columnLength <= maxLength) {
+		return strings.Join(columns, ", ")
+	}
+
+	for i, col := range columns {
+		columns[i] = fmt.Sprintf("%s(%d)", col, maxLength)
+	}
+	return strings.Join(columns, ", ")
+}
@@ -0,0 +1,80 @@
+package casbun
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/uptrace/bun/dialect"
+)
+
+func TestUniqueIndexColumns(t *testing.T) {
+	tests := []struct {
+		name         string
+		dialect      dialect.Name
+		columnLength int
+		columns      []string
+		want         string
+	}{
+		{
+			name:         "default length on mysql",
+			dialect:      dialect.MySQL,
+			columnLength: defaultColumnLength,
+			want:         "ptype, v0, v1, v2, v3, v4, v5",
+		},
+		{
+			name:         "long columns on mysql",
+			dialect:      dialect.MySQL,
+			columnLength: 255,
+			want:         "ptype(109), v0(109), v1(109), v2(109), v3(109), v4(109), v5(109)",
+		},
+		{
+			name:         "unbounded columns on mysql",
+			dialect:      dialect.MySQL,
+			columnLength: 0,
+			want:         "ptype(109), v0(109), v1(109), v2(109), v3(109), v4(109), v5(109)",
+		},
+		{
+			name:         "long columns on postgres",
+			dialect:      dialect.PG,
+			columnLength: 255,
+			want:         "ptype, v0, v1, v2, v3, v4, v5",
+		},
+		{
+			name:         "long unique columns on mysql",
+			dialect:      dialect.MySQL,
+			columnLength: 255,
+			columns:      []string{"ptype", "v0", "v1"},
+			want:         "ptype, v0, v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns := tt.columns
+			if columns == nil {
+				columns = append([]string{"ptype"}, valueColumns...)
+			}
+			got := uniqueIndexColumns(tt.dialect, tt.columnLength, columns)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+
+			if tt.dialect != dialect.MySQL {
+				return
+			}
+
+			// Every indexed character may take 4 bytes in utf8mb4.
+			keyBytes := 0
+			for _, m := range regexp.MustCompile(`\((\d+)\)`).FindAllStringSubmatch(got, -1) {
+				n, _ := strconv.Atoi(m[1])
+				keyBytes += n * mysqlMaxCharBytes
+			}
+			if keyBytes == 0 {
+				keyBytes = len(columns) * tt.columnLength * mysqlMaxCharBytes
+			}
+			if keyBytes > mysqlMaxIndexBytes {
+				t.Errorf("index key takes %d bytes, more than %d", keyBytes, mysqlMaxIndexBytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package casbun
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// ExportJSON writes every stored policy to w as a JSON array of CasbinPolicy
+// objects, ordered like Snapshot, for web tooling. Each object holds the id,
+// the ptype and the used values, such as
+// {"id":1,"ptype":"p","v0":"alice","v1":"data1","v2":"read"}.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "application/json")
+//	if err := adapter.ExportJSON(r.Context(), w); err != nil {
+//	    http.Error(w, err.Error(), http.StatusInternalServerError)
+//	}
+func (a *Adapter) ExportJSON(ctx context.Context, w io.Writer) (err error) {
+	defer wrapOpError("export_json", "", &err)
+
+	policies, err := a.Snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(policies)
+}
+
+// ImportJSON replaces every stored policy with the policies read from r, a
+// JSON array of CasbinPolicy objects as written by ExportJSON, like Restore.
+// Nothing is changed if r does not hold valid JSON.
+//
+// Example:
+//
+//	if err := adapter.ImportJSON(r.Context(), r.Body); err != nil {
+//	    http.Error(w, err.Error(), http.StatusBadRequest)
+//	}
+func (a *Adapter) ImportJSON(ctx context.Context, r io.Reader) (err error) {
+	defer wrapOpError("import_json", "", &err)
+
+	var policies []CasbinPolicy
+	if err := json.NewDecoder(r).Decode(&policies); err != nil {
+		return err
+	}
+	return a.Restore(ctx, policies)
+}
@@ -0,0 +1,115 @@
+package casbun_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestExportImportJSON(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	want, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := adapter.ExportJSON(ctx, &buf); err != nil {
+		t.Fatalf("unable to export policies: %v", err)
+	}
+	if first := `{"id":1,"ptype":"p","v0":"alice","v1":"data1","v2":"read"}`; !strings.Contains(buf.String(), first) {
+		t.Errorf("got JSON %s, want it to contain %s", buf.String(), first)
+	}
+
+	imported, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := imported.ImportJSON(ctx, &buf); err != nil {
+		t.Fatalf("unable to import policies: %v", err)
+	}
+
+	got, err := imported.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(withoutIDs(got), withoutIDs(want)) {
+		t.Errorf("got imported policies %+v, want %+v", got, want)
+	}
+
+	if err := imported.ImportJSON(ctx, strings.NewReader(`[{"ptype":`)); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+	if got, err := imported.Snapshot(ctx); err != nil || len(got) != len(want) {
+		t.Errorf("got %d policies and error %v after the failed import, want %d", len(got), err, len(want))
+	}
+}
+
+func TestExportImportJSONPTypeTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	opt := casbun.WithPTypeTable(map[string]string{"g": "casbin_groups"})
+	adapter, err := casbun.NewAdapter(ctx, initDB(), opt)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := adapter.ExportJSON(ctx, &buf); err != nil {
+		t.Fatalf("unable to export policies: %v", err)
+	}
+	if group := `"ptype":"g","v0":"alice","v1":"admin"`; !strings.Contains(buf.String(), group) {
+		t.Errorf("got JSON %s, want it to contain the routed rule %s", buf.String(), group)
+	}
+
+	db := initDB()
+	imported, err := casbun.NewAdapter(ctx, db, opt)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := imported.ImportJSON(ctx, &buf); err != nil {
+		t.Fatalf("unable to import policies: %v", err)
+	}
+
+	var groups int
+	if err := db.NewRaw("SELECT count(*) FROM casbin_groups").Scan(ctx, &groups); err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	if groups != 1 {
+		t.Errorf("got %d rows in casbin_groups, want 1", groups)
+	}
+	if err := imported.RemovePolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to remove grouping policy: %v", err)
+	}
+	got, err := imported.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if len(got) != 1 || got[0].PType != "p" {
+		t.Errorf("got policies %+v after removing the grouping policy, want the p rule only", got)
+	}
+}
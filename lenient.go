@@ -0,0 +1,113 @@
+package casbun
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/uptrace/bun"
+)
+
+// ImportFailure records a batch of policies that RestoreLenient or
+// ImportJSONLenient could not store.
+type ImportFailure struct {
+	// Policies is the batch of policies, none of which was stored.
+	Policies []CasbinPolicy
+	// Err is the error storing the batch, such as a unique violation.
+	Err error
+}
+
+// RestoreLenient replaces every stored policy with policies like Restore, but
+// a batch of policies that fails to be inserted, for instance because it
+// holds a rule twice, does not abort the restore: each batch is inserted on a
+// savepoint of the transaction, which only rolls back the failed batch, and
+// the other batches are committed. With WithPTypeTable, the batches are made
+// per table. The failed batches are returned along with their error. The
+// returned error is only set if the restore was aborted, in which case
+// nothing is changed.
+//
+// Example:
+//
+//	failures, err := adapter.RestoreLenient(ctx, policies)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, failure := range failures {
+//	    log.Printf("skipped %d policies: %v", len(failure.Policies), failure.Err)
+//	}
+func (a *Adapter) RestoreLenient(ctx context.Context, policies []CasbinPolicy) (failures []ImportFailure, err error) {
+	defer wrapOpError("restore_lenient", "", &err)
+	defer a.observe("restore_lenient")()
+
+	if err := a.checkAllowedPolicies(policies); err != nil {
+		return nil, err
+	}
+
+	rows := make([]CasbinPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if !a.preserveIDs {
+			policy.ID = 0
+		}
+		rows = append(rows, policy)
+	}
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		failures = nil
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				routes, grouped := a.routePolicies(rows)
+				for i, r := range routes {
+					if _, err := r.newDelete(ctx, tx).
+						Where("1 = 1").
+						ApplyQueryBuilder(r.allowedPTypes).
+						Exec(ctx); err != nil {
+						return err
+					}
+
+					rows := grouped[i]
+					for start := 0; start < len(rows); start += insertBatchSize {
+						batch := rows[start:min(start+insertBatchSize, len(rows))]
+						// RunInTx on a transaction runs on a savepoint, written
+						// for the dialect by Bun.
+						if err := tx.RunInTx(ctx, nil, func(ctx context.Context, sp bun.Tx) error {
+							_, err := r.newInsert(ctx, sp, &batch).
+								Exec(ctx)
+							return err
+						}); err != nil {
+							failures = append(failures, ImportFailure{Policies: batch, Err: insertError(err)})
+						}
+					}
+					if r.preserveIDs {
+						if err := r.resetIDSequence(ctx, tx); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			},
+		)
+	})
+	if err := a.notify(ctx, err, PolicyEvent{Op: EventSave}); err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// ImportJSONLenient replaces every stored policy with the policies read from
+// r, as written by ExportJSON, like RestoreLenient. Nothing is changed if r
+// does not hold valid JSON.
+//
+// Example:
+//
+//	failures, err := adapter.ImportJSONLenient(ctx, f)
+func (a *Adapter) ImportJSONLenient(ctx context.Context, r io.Reader) (_ []ImportFailure, err error) {
+	defer wrapOpError("import_json_lenient", "", &err)
+
+	var policies []CasbinPolicy
+	if err := json.NewDecoder(r).Decode(&policies); err != nil {
+		return nil, err
+	}
+	return a.RestoreLenient(ctx, policies)
+}
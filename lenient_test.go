@@ -0,0 +1,130 @@
+package casbun_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestImportJSONLenient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"old", "data", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	// Three batches, the second of which holds a rule twice.
+	policies := make([]casbun.CasbinPolicy, 0, 750)
+	for i := 0; i < 750; i++ {
+		policies = append(policies, casbun.CasbinPolicy{PType: "p", V0: fmt.Sprintf("user%d", i), V1: "data", V2: "read"})
+	}
+	policies[300].V0 = policies[260].V0
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(policies); err != nil {
+		t.Fatalf("unable to encode policies: %v", err)
+	}
+	failures, err := adapter.ImportJSONLenient(ctx, &buf)
+	if err != nil {
+		t.Fatalf("unable to import policies: %v", err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("got %d failed batches, want 1", len(failures))
+	}
+	if !errors.Is(failures[0].Err, casbun.ErrPolicyExists) {
+		t.Errorf("got error %v for the failed batch, want %v", failures[0].Err, casbun.ErrPolicyExists)
+	}
+	if got := failures[0].Policies; len(got) != 250 || got[0].V0 != "user250" {
+		t.Errorf("got failed batch of %d policies starting at %+v, want the second batch", len(got), got[0])
+	}
+
+	got, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if len(got) != 500 {
+		t.Fatalf("got %d stored policies, want 500", len(got))
+	}
+	if got[0].V0 != "user0" || got[250].V0 != "user500" {
+		t.Errorf("got stored policies starting at %+v and %+v, want the first and third batches", got[0], got[250])
+	}
+}
+
+func TestRestoreLenientPTypeTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPTypeTable(map[string]string{"g": "casbin_groups"}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"old", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	failures, err := adapter.RestoreLenient(ctx, []casbun.CasbinPolicy{
+		{PType: "p", V0: "admin", V1: "data1", V2: "read"},
+		{PType: "g", V0: "alice", V1: "admin"},
+	})
+	if err != nil {
+		t.Fatalf("unable to restore policies: %v", err)
+	}
+	if len(failures) != 0 {
+		t.Errorf("got failed batches %+v, want none", failures)
+	}
+
+	var policies, groups []casbun.CasbinPolicy
+	if err := db.NewSelect().Model(&policies).Scan(ctx); err != nil {
+		t.Fatalf("unable to read rows: %v", err)
+	}
+	if err := db.NewSelect().Model(&groups).ModelTableExpr("casbin_groups AS cp").Scan(ctx); err != nil {
+		t.Fatalf("unable to read rows: %v", err)
+	}
+	if len(policies) != 1 || policies[0].PType != "p" {
+		t.Errorf("got rows %+v in casbin_policies, want the p rule only", policies)
+	}
+	if len(groups) != 1 || groups[0].V0 != "alice" {
+		t.Errorf("got rows %+v in casbin_groups, want the restored g rule only", groups)
+	}
+}
+
+func TestRestoreLenientPTypeFilter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPTypeFilter("p"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	foreign := &casbun.CasbinPolicy{PType: "x", V0: "job", V1: "nightly"}
+	if _, err := db.NewInsert().Model(foreign).Exec(ctx); err != nil {
+		t.Fatalf("unable to insert foreign row: %v", err)
+	}
+
+	if _, err := adapter.RestoreLenient(ctx, []casbun.CasbinPolicy{{PType: "p", V0: "alice", V1: "data1", V2: "read"}}); err != nil {
+		t.Fatalf("unable to restore policies: %v", err)
+	}
+	if _, err := adapter.RestoreLenient(ctx, []casbun.CasbinPolicy{{PType: "x", V0: "job", V1: "hourly"}}); err == nil {
+		t.Errorf("expected an error restoring a policy of a filtered out ptype")
+	}
+
+	count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("got %d rows, want the restored rule and the foreign row", count)
+	}
+}
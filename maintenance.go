@@ -0,0 +1,51 @@
+package casbun
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// Reindex rebuilds the indexes of the policy table.
+// It is meant to be run periodically after heavy churn, when the unique and
+// ptype indexes may have bloated.
+//
+// On SQLite and PostgreSQL the indexes are rebuilt with REINDEX, on MySQL the
+// table is rebuilt with OPTIMIZE TABLE, on MSSQL every index is rebuilt in
+// place and on Oracle the table statistics are refreshed with ANALYZE TABLE.
+func (a *Adapter) Reindex(ctx context.Context) (err error) {
+	defer wrapOpError("reindex", "", &err)
+	features := a.dialect()
+	if features.reindex == "" {
+		return fmt.Errorf("casbun: reindex is not supported on %s", features.name)
+	}
+
+	if _, err := a.conn(ctx).NewRaw(features.reindex, bun.Ident(a.tableName)).
+		Comment(a.queryComment(ctx)).
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Vacuum reclaims the storage left behind by deleted policy rows.
+//
+// On SQLite this vacuums the whole database file, as SQLite has no per-table
+// vacuum. It must not be called while a transaction is open.
+func (a *Adapter) Vacuum(ctx context.Context) (err error) {
+	defer wrapOpError("vacuum", "", &err)
+	features := a.dialect()
+	if features.vacuum == "" {
+		return fmt.Errorf("casbun: vacuum is not supported on %s", features.name)
+	}
+
+	if _, err := a.conn(ctx).NewRaw(features.vacuum, bun.Ident(a.tableName)).
+		Comment(a.queryComment(ctx)).
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
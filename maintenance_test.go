@@ -0,0 +1,40 @@
+package casbun_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestReindex(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	adapter, err := casbun.NewAdapter(context.Background(), db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rules := make([][]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+	}
+
+	if err := adapter.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	if err := adapter.RemovePolicies("p", "p", rules[:50]); err != nil {
+		t.Fatalf("failed to remove policies: %v", err)
+	}
+
+	if err := adapter.Reindex(context.Background()); err != nil {
+		t.Fatalf("unable to reindex: %v", err)
+	}
+
+	if err := adapter.Vacuum(context.Background()); err != nil {
+		t.Fatalf("unable to vacuum: %v", err)
+	}
+}
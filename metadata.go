@@ -0,0 +1,115 @@
+package casbun
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/uptrace/bun"
+)
+
+// WithMetadataColumn adds the free-text column col to the policy table, to
+// annotate why a rule exists, such as a ticket number or the granting admin.
+// The column is not part of the Casbin rule: loads ignore it and the other
+// operations still match rules on their values only. The metadata is set by
+// AddPolicyWithMetadata and read by GetMetadata. NewAdapter fails if col is
+// empty.
+//
+// The column is created with the table, so an existing table must be altered
+// to add it. Saving the policy with SaveTruncate, the default strategy,
+// rewrites every row and so drops the metadata.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithMetadataColumn("comment"))
+func WithMetadataColumn(col string) CasbinBunOption {
+	return func(a *Adapter) {
+		if col == "" {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: metadata column must not be empty"))
+			return
+		}
+		a.metadataColumn = col
+	}
+}
+
+// AddPolicyWithMetadata adds a policy rule to the storage like AddPolicyCtx,
+// and stores meta in the column set by WithMetadataColumn.
+//
+// Example:
+//
+//	err := adapter.AddPolicyWithMetadata(ctx, "p", []string{"alice", "data1", "read"}, "granted by bob, TICKET-42")
+func (a *Adapter) AddPolicyWithMetadata(ctx context.Context, ptype string, rule []string, meta string) (err error) {
+	defer wrapOpError("add_policy_with_metadata", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.AddPolicyWithMetadata(ctx, ptype, rule, meta)
+	}
+	defer a.observe("add_policy_with_metadata")()
+
+	if a.metadataColumn == "" {
+		return errors.New("casbun: no metadata column, see WithMetadataColumn")
+	}
+	if err := a.checkRules(ptype, [][]string{rule}); err != nil {
+		return err
+	}
+	event := PolicyEvent{Op: EventAdd, PType: ptype, Rules: [][]string{rule}}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
+	newPolicy := newCasbinPolicy(ptype, rule)
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				if _, err := a.newInsert(ctx, tx, &newPolicy).
+					Exec(ctx); err != nil {
+					return insertError(err)
+				}
+
+				_, err := tx.NewUpdate().
+					Comment(a.queryComment(ctx)).
+					ModelTableExpr("?", bun.Ident(a.tableName)).
+					Set("? = ?", bun.Ident(a.metadataColumn), meta).
+					ApplyQueryBuilder(keyCondition(newPolicy)).
+					Exec(ctx)
+				return err
+			},
+		)
+	})
+	return a.notify(ctx, err, event)
+}
+
+// GetMetadata returns the metadata stored with a policy rule of ptype, which
+// is empty when none was set. It returns sql.ErrNoRows if the rule is not
+// stored.
+//
+// Example:
+//
+//	meta, err := adapter.GetMetadata(ctx, "p", []string{"alice", "data1", "read"})
+func (a *Adapter) GetMetadata(ctx context.Context, ptype string, rule []string) (_ string, err error) {
+	defer wrapOpError("get_metadata", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.GetMetadata(ctx, ptype, rule)
+	}
+	defer a.observe("get_metadata")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return "", err
+	}
+
+	if a.metadataColumn == "" {
+		return "", errors.New("casbun: no metadata column, see WithMetadataColumn")
+	}
+
+	var meta sql.NullString
+	if err := a.reader(ctx).NewSelect().
+		Comment(a.queryComment(ctx)).
+		ModelTableExpr("?", bun.Ident(a.tableName)).
+		ColumnExpr("?", bun.Ident(a.metadataColumn)).
+		ApplyQueryBuilder(keyCondition(newCasbinPolicy(ptype, rule))).
+		Scan(ctx, &meta); err != nil {
+		return "", err
+	}
+	return meta.String, nil
+}
@@ -0,0 +1,87 @@
+package casbun_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestMetadataColumn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []casbun.CasbinBunOption
+	}{
+		{name: "default"},
+		{name: "composite key", opts: []casbun.CasbinBunOption{casbun.WithCompositeKey()}},
+		{name: "uuid key", opts: []casbun.CasbinBunOption{casbun.WithUUIDKey()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			db := initDB()
+			opts := append([]casbun.CasbinBunOption{casbun.WithMetadataColumn("comment")}, tt.opts...)
+			adapter, err := casbun.NewAdapter(ctx, db, opts...)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			annotated := []string{"alice", "data1", "read"}
+			plain := []string{"bob", "data1", "read"}
+			if err := adapter.AddPolicyWithMetadata(ctx, "p", annotated, "TICKET-42"); err != nil {
+				t.Fatalf("failed to add policy with metadata: %v", err)
+			}
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", plain); err != nil {
+				t.Fatalf("failed to add policy: %v", err)
+			}
+
+			meta, err := adapter.GetMetadata(ctx, "p", annotated)
+			if err != nil {
+				t.Fatalf("unable to get metadata: %v", err)
+			}
+			if meta != "TICKET-42" {
+				t.Errorf("got metadata %q, want %q", meta, "TICKET-42")
+			}
+			meta, err = adapter.GetMetadata(ctx, "p", plain)
+			if err != nil {
+				t.Fatalf("unable to get metadata: %v", err)
+			}
+			if meta != "" {
+				t.Errorf("got metadata %q, want none", meta)
+			}
+			if _, err := adapter.GetMetadata(ctx, "p", []string{"carol", "data1", "read"}); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("got error %v, want %v", err, sql.ErrNoRows)
+			}
+
+			if err := adapter.AddPolicyWithMetadata(ctx, "p", annotated, "again"); !errors.Is(err, casbun.ErrPolicyExists) {
+				t.Errorf("got error %v, want %v", err, casbun.ErrPolicyExists)
+			}
+
+			m, _ := model.NewModelFromString(modelStr)
+			e, err := casbin.NewEnforcer(m, adapter)
+			if err != nil {
+				t.Fatalf("failed to create enforcer: %v", err)
+			}
+			ensureHasPolicy(t, db, e, [][]string{annotated, plain})
+
+			if err := adapter.RemovePolicyCtx(ctx, "p", "p", annotated); err != nil {
+				t.Fatalf("failed to remove policy: %v", err)
+			}
+			if _, err := adapter.GetMetadata(ctx, "p", annotated); !errors.Is(err, sql.ErrNoRows) {
+				t.Errorf("got error %v, want %v", err, sql.ErrNoRows)
+			}
+		})
+	}
+
+	if _, err := casbun.NewAdapter(context.Background(), initDB(), casbun.WithMetadataColumn("")); err == nil {
+		t.Errorf("expected an error for an empty metadata column")
+	}
+}
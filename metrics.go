@@ -0,0 +1,50 @@
+package casbun
+
+import "time"
+
+// Metrics receives the measurements of the adapter's operations, for export
+// to a monitoring system such as Prometheus.
+//
+// Operations are named after the adapter method in snake case, without the
+// Ctx suffix, e.g. "load_policy", "add_policies" or
+// "remove_filtered_policy". Failed operations are counted too.
+type Metrics interface {
+	// IncOp counts one call of the operation name.
+	IncOp(name string)
+	// ObserveLatency records the duration of one call of the operation name.
+	ObserveLatency(name string, d time.Duration)
+	// SetPolicyCount records the number of policy rules read by the last load.
+	SetPolicyCount(n int)
+}
+
+// WithMetrics reports the measurements of the adapter's operations to metrics.
+// A nil metrics discards them, which is the default.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithMetrics(prometheusMetrics))
+func WithMetrics(metrics Metrics) CasbinBunOption {
+	return func(a *Adapter) {
+		if metrics == nil {
+			metrics = noopMetrics{}
+		}
+		a.metrics = metrics
+	}
+}
+
+// noopMetrics discards every measurement, when WithMetrics is not used.
+type noopMetrics struct{}
+
+func (noopMetrics) IncOp(string)                         {}
+func (noopMetrics) ObserveLatency(string, time.Duration) {}
+func (noopMetrics) SetPolicyCount(int)                   {}
+
+// observe counts a call of the operation name and returns the function
+// recording its latency, to be deferred.
+func (a *Adapter) observe(name string) func() {
+	a.metrics.IncOp(name)
+	start := time.Now()
+	return func() {
+		a.metrics.ObserveLatency(name, time.Since(start))
+	}
+}
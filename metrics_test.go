@@ -0,0 +1,93 @@
+package casbun_test
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+// recordingMetrics records the measurements reported by an adapter.
+type recordingMetrics struct {
+	mu          sync.Mutex
+	ops         []string
+	latencies   map[string]int
+	policyCount int
+}
+
+func (m *recordingMetrics) IncOp(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ops = append(m.ops, name)
+}
+
+func (m *recordingMetrics) ObserveLatency(name string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latencies[name]++
+}
+
+func (m *recordingMetrics) SetPolicyCount(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policyCount = n
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := &recordingMetrics{latencies: make(map[string]int)}
+	adapter, err := casbun.NewAdapter(context.Background(), initDB(), casbun.WithMetrics(metrics))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	if _, err := e.AddPolicy("alice", "data1", "read"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if _, err := e.AddPolicies([][]string{{"bob", "data1", "read"}, {"carol", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if _, err := e.UpdatePolicy([]string{"alice", "data1", "read"}, []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("failed to update policy: %v", err)
+	}
+	if _, err := e.RemovePolicy("bob", "data1", "read"); err != nil {
+		t.Fatalf("failed to remove policy: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+
+	wantOps := []string{
+		"load_policy",
+		"add_policy",
+		"add_policies",
+		"update_policy",
+		"remove_policy",
+		"load_policy",
+	}
+	if !slices.Equal(metrics.ops, wantOps) {
+		t.Errorf("got operations %v, want %v", metrics.ops, wantOps)
+	}
+
+	for _, op := range wantOps {
+		if metrics.latencies[op] == 0 {
+			t.Errorf("no latency observed for %s", op)
+		}
+	}
+
+	if metrics.policyCount != 2 {
+		t.Errorf("got policy count %d, want 2", metrics.policyCount)
+	}
+}
@@ -0,0 +1,107 @@
+package casbun
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/uptrace/bun"
+)
+
+// PolicyRow is a row of the policy table declared by a model of the user, see
+// WithModel. The model maps the ptype and v0 to v5 columns like CasbinPolicy,
+// and may declare any other column, such as a tenant or an audit column.
+type PolicyRow interface {
+	// RulePType returns the ptype of the rule stored by the row.
+	RulePType() string
+	// RuleValues returns the values of the rule stored by the row, v0 to v5.
+	RuleValues() []string
+	// SetRule sets the rule stored by the row.
+	SetRule(ptype string, values []string)
+}
+
+var _ PolicyRow = (*CasbinPolicy)(nil)
+
+// RulePType returns the ptype of the rule stored by the row.
+func (c *CasbinPolicy) RulePType() string {
+	return c.PType
+}
+
+// RuleValues returns the values of the rule stored by the row, v0 to v5.
+func (c *CasbinPolicy) RuleValues() []string {
+	return c.values()
+}
+
+// SetRule sets the rule stored by the row.
+func (c *CasbinPolicy) SetRule(ptype string, values []string) {
+	*c = newCasbinPolicy(ptype, values)
+}
+
+// WithModel stores the policies in rows of the model returned by newRow
+// instead of CasbinPolicy, to keep the rules in a larger row with columns of
+// its own. newRow must return a new pointer to a bun model on each call,
+// initialized with the values of the other columns, which the adapter writes
+// along with the rule. The table is created from the model and loads scan the
+// rows into it, while the other operations match rules on the policy columns
+// only. The table is still named by WithTableName, and WithColumnType and
+// WithColumnLength do not apply to it.
+//
+// The model must be keyed on an autoincrement id, and NewAdapter fails if
+// WithModel is combined with WithCompositeKey, WithUUIDKey,
+// WithInsertIgnoreEmptyTrailing or WithPreserveIDs.
+//
+// Example:
+//
+//	type TenantPolicy struct {
+//	    casbun.CasbinPolicy `bun:",extend"`
+//	    TenantID            int64 `bun:"tenant_id,notnull"`
+//	}
+//
+//	adapter, err := NewAdapter(ctx, db, WithModel(func() PolicyRow {
+//	    return &TenantPolicy{TenantID: tenantID}
+//	}))
+func WithModel(newRow func() PolicyRow) CasbinBunOption {
+	return func(a *Adapter) {
+		if newRow == nil {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: model constructor must not be nil"))
+			return
+		}
+		if typ := reflect.TypeOf(newRow()); typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: model must be a pointer to a struct"))
+			return
+		}
+		a.newRow = newRow
+	}
+}
+
+// newModelRows returns a pointer to a slice of rows of the model set by
+// WithModel, storing policies.
+func (a *Adapter) newModelRows(policies []CasbinPolicy) interface{} {
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(a.newRow())))
+	for _, p := range policies {
+		row := a.newRow()
+		row.SetRule(p.PType, p.values())
+		rows.Elem().Set(reflect.Append(rows.Elem(), reflect.ValueOf(row)))
+	}
+	return rows.Interface()
+}
+
+// modelRowPolicies returns the policies stored by rows, a pointer to a slice of
+// rows of the model set by WithModel.
+func modelRowPolicies(rows interface{}) []CasbinPolicy {
+	slice := reflect.ValueOf(rows).Elem()
+	policies := make([]CasbinPolicy, 0, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		row := slice.Index(i).Interface().(PolicyRow)
+		policies = append(policies, newCasbinPolicy(row.RulePType(), row.RuleValues()))
+	}
+	return policies
+}
+
+// newModelCreateTable returns the statement creating the policy table from
+// the model set by WithModel.
+func (a *Adapter) newModelCreateTable(db bun.IDB) *bun.CreateTableQuery {
+	return a.withExtraColumns(db.NewCreateTable().
+		Model(a.newRow()).
+		ModelTableExpr("?", bun.Ident(a.tableName)).
+		IfNotExists())
+}
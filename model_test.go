@@ -0,0 +1,72 @@
+package casbun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+// tenantPolicy is a policy row with a column of its own.
+type tenantPolicy struct {
+	casbun.CasbinPolicy `bun:",extend"`
+	TenantID            int64 `bun:"tenant_id,notnull"`
+}
+
+func TestWithModel(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithModel(func() casbun.PolicyRow {
+		return &tenantPolicy{TenantID: 7}
+	}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, err := model.NewModelFromString(modelStr)
+	if err != nil {
+		t.Fatalf("unable to create model: %v", err)
+	}
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("unable to create enforcer: %v", err)
+	}
+	if _, err := e.AddPolicies([][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if _, err := e.AddGroupingPolicy("carol", "alice"); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("failed to save policy: %v", err)
+	}
+
+	count, err := db.NewSelect().
+		TableExpr("casbin_policies").
+		Where("tenant_id = 7").
+		Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("got %d rules of the tenant, want 3", count)
+	}
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}})
+	if ok, err := e.Enforce("carol", "data1", "read"); err != nil || !ok {
+		t.Errorf("got %v, %v for the inherited rule, want true", ok, err)
+	}
+
+	if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithModel(func() casbun.PolicyRow {
+		return &tenantPolicy{}
+	}), casbun.WithCompositeKey()); err == nil {
+		t.Errorf("expected an error combining a model with a composite key")
+	}
+}
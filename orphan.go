@@ -0,0 +1,136 @@
+package casbun
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/uptrace/bun"
+)
+
+// FindOrphanGroupings returns the stored grouping rules, of the ptypes
+// starting with g, whose role grants nothing: the role is not the subject of
+// any policy rule, of the ptypes starting with p, and does not inherit, in
+// turn, from a role that is. Such rules, e.g. left behind when the policies of
+// a role were removed, no longer affect any decision. Each rule is returned
+// with its ptype first, such as ["g", "alice", "archived"].
+//
+// Example:
+//
+//	orphans, err := adapter.FindOrphanGroupings(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("%d grouping rules grant nothing", len(orphans))
+func (a *Adapter) FindOrphanGroupings(ctx context.Context) (_ [][]string, err error) {
+	defer wrapOpError("find_orphan_groupings", "", &err)
+	defer a.observe("find_orphan_groupings")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	orphans, err := a.orphanGroupings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]string, 0, len(orphans))
+	for _, policy := range orphans {
+		out = append(out, policy.toSlice())
+	}
+	return out, nil
+}
+
+// PruneOrphanGroupings removes the grouping rules reported by
+// FindOrphanGroupings, like RemovePolicies for each of their ptypes, and
+// returns the number of rules removed. The rules are found and removed in a
+// single transaction.
+//
+// Example:
+//
+//	pruned, err := adapter.PruneOrphanGroupings(ctx)
+func (a *Adapter) PruneOrphanGroupings(ctx context.Context) (pruned int64, err error) {
+	defer wrapOpError("prune_orphan_groupings", "", &err)
+	defer a.observe("prune_orphan_groupings")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return 0, err
+	}
+
+	err = a.conn(ctx).RunInTx(ctx, a.txOptions(), func(ctx context.Context, tx bun.Tx) error {
+		ctx = WithTx(ctx, tx)
+		orphans, err := a.orphanGroupings(ctx)
+		if err != nil {
+			return err
+		}
+
+		rules := make(map[string][][]string)
+		for _, policy := range orphans {
+			rules[policy.PType] = append(rules[policy.PType], policy.values())
+		}
+		gtypes := make([]string, 0, len(rules))
+		for gtype := range rules {
+			gtypes = append(gtypes, gtype)
+		}
+		sort.Strings(gtypes)
+
+		pruned = 0
+		for _, gtype := range gtypes {
+			if err := a.RemovePoliciesCtx(ctx, "g", gtype, rules[gtype]); err != nil {
+				return err
+			}
+			pruned += int64(len(rules[gtype]))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// orphanGroupings returns the stored grouping rules whose role grants
+// nothing, see FindOrphanGroupings.
+func (a *Adapter) orphanGroupings(ctx context.Context) ([]CasbinPolicy, error) {
+	var policies []CasbinPolicy
+	for _, r := range a.routes() {
+		routed, err := r.selectLoadedPolicies(ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q
+		})
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, routed...)
+	}
+
+	// A role grants something if it is the subject of a policy rule, or if it
+	// inherits from such a role. The inheritance is followed until no role is
+	// added.
+	granting := make(map[string]bool)
+	var groupings []CasbinPolicy
+	for _, policy := range policies {
+		switch {
+		case strings.HasPrefix(policy.PType, "p"):
+			granting[policy.V0] = true
+		case strings.HasPrefix(policy.PType, "g"):
+			groupings = append(groupings, policy)
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, policy := range groupings {
+			if granting[policy.V1] && !granting[policy.V0] {
+				granting[policy.V0] = true
+				changed = true
+			}
+		}
+	}
+
+	orphans := make([]CasbinPolicy, 0)
+	for _, policy := range groupings {
+		if !granting[policy.V1] {
+			orphans = append(orphans, policy)
+		}
+	}
+	return orphans, nil
+}
@@ -0,0 +1,68 @@
+package casbun_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestOrphanGroupings(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	groupings := [][]string{
+		{"alice", "admin"},
+		// superadmin grants something by inheriting from admin.
+		{"superadmin", "admin"},
+		{"bob", "superadmin"},
+		{"carol", "ghost"},
+		{"dave", "ghost"},
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "g", "g", groupings); err != nil {
+		t.Fatalf("failed to add grouping policies: %v", err)
+	}
+
+	orphans, err := adapter.FindOrphanGroupings(ctx)
+	if err != nil {
+		t.Fatalf("failed to find orphan groupings: %v", err)
+	}
+	want := [][]string{{"g", "carol", "ghost"}, {"g", "dave", "ghost"}}
+	if !reflect.DeepEqual(orphans, want) {
+		t.Errorf("got orphan groupings %v, want %v", orphans, want)
+	}
+
+	pruned, err := adapter.PruneOrphanGroupings(ctx)
+	if err != nil {
+		t.Fatalf("failed to prune orphan groupings: %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("got %d pruned groupings, want 2", pruned)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	if got, _ := m.GetPolicy("g", "g"); !reflect.DeepEqual(got, groupings[:3]) {
+		t.Errorf("got grouping policies %v, want %v", got, groupings[:3])
+	}
+
+	orphans, err = adapter.FindOrphanGroupings(ctx)
+	if err != nil {
+		t.Fatalf("failed to find orphan groupings: %v", err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("got orphan groupings %v after pruning, want none", orphans)
+	}
+}
@@ -0,0 +1,87 @@
+package casbun
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// RuleResult is the outcome of a rule passed to AddPoliciesPartial.
+type RuleResult struct {
+	// Rule is the rule, as passed to AddPoliciesPartial.
+	Rule []string
+	// Err is the reason the rule was rejected, or nil if it is stored.
+	Err error
+	// Existed reports a rule that was skipped as it was already stored, or
+	// appeared earlier in the rules.
+	Existed bool
+}
+
+// AddPoliciesPartial adds the rules of ptype to the storage, like
+// AddPoliciesIgnoreExisting, but skips the rules failing validation instead
+// of aborting: a rule holding no value, see ErrEmptyRule, or rejected by the
+// validator set by WithRowValidator. It returns a result per rule, in the
+// order of rules, holding the validation error of the skipped ones. A rule
+// that is already stored is not an error, and its result reports that it
+// existed. The stored rules are checked and the new ones inserted in a single
+// transaction.
+//
+// The returned error is only set if the valid rules could not be stored, in
+// which case none of them was.
+//
+// Example:
+//
+//	results, err := adapter.AddPoliciesPartial(ctx, "p", rules)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, res := range results {
+//	    if res.Err != nil {
+//	        log.Printf("skipped %v: %v", res.Rule, res.Err)
+//	    }
+//	}
+func (a *Adapter) AddPoliciesPartial(ctx context.Context, ptype string, rules [][]string) (_ []RuleResult, err error) {
+	defer wrapOpError("add_policies_partial", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.AddPoliciesPartial(ctx, ptype, rules)
+	}
+	defer a.observe("add_policies_partial")()
+
+	results := make([]RuleResult, 0, len(rules))
+	valid := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		err := a.checkRules(ptype, [][]string{rule})
+		if err == nil {
+			valid = append(valid, rule)
+		}
+		results = append(results, RuleResult{Rule: rule, Err: err})
+	}
+
+	err = a.conn(ctx).RunInTx(ctx, a.txOptions(), func(ctx context.Context, tx bun.Tx) error {
+		ctx = WithTx(ctx, tx)
+		existing, err := a.ExistingPolicies(ctx, ptype, valid)
+		if err != nil {
+			return err
+		}
+
+		stored := make(map[[7]string]bool, len(existing))
+		for _, rule := range existing {
+			stored[newCasbinPolicy(ptype, rule).key()] = true
+		}
+		for i := range results {
+			if results[i].Err != nil {
+				continue
+			}
+			key := newCasbinPolicy(ptype, results[i].Rule).key()
+			results[i].Existed = stored[key]
+			stored[key] = true
+		}
+
+		_, err = a.AddPoliciesIgnoreExisting(ctx, ptype, valid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
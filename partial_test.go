@@ -0,0 +1,76 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestAddPoliciesPartial(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	errForbidden := errors.New("forbidden subject")
+	adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithRowValidator(
+		func(_ string, rule []string) error {
+			if rule[0] == "mallory" {
+				return errForbidden
+			}
+			return nil
+		},
+	))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	rules := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"mallory", "data1", "write"},
+		{"", "", ""},
+		{"carol", "data3", "read"},
+		{"carol", "data3", "read"},
+	}
+	results, err := adapter.AddPoliciesPartial(ctx, "p", rules)
+	if err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if len(results) != len(rules) {
+		t.Fatalf("got %d results, want %d", len(results), len(rules))
+	}
+	wantErrs := []error{nil, nil, errForbidden, casbun.ErrEmptyRule, nil, nil}
+	// The first rule was already stored, and the last one repeats a rule.
+	wantExisted := []bool{true, false, false, false, false, true}
+	for i, res := range results {
+		if !reflect.DeepEqual(res.Rule, rules[i]) {
+			t.Errorf("got rule %v for result %d, want %v", res.Rule, i, rules[i])
+		}
+		if wantErrs[i] == nil && res.Err != nil || !errors.Is(res.Err, wantErrs[i]) {
+			t.Errorf("got error %v for rule %v, want %v", res.Err, rules[i], wantErrs[i])
+		}
+		if res.Existed != wantExisted[i] {
+			t.Errorf("got existed %t for rule %d %v, want %t", res.Existed, i, rules[i], wantExisted[i])
+		}
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	want := [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"carol", "data3", "read"},
+	}
+	if got, _ := m.GetPolicy("p", "p"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got policies %v, want %v", got, want)
+	}
+}
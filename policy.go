@@ -8,16 +8,23 @@ import (
 
 // CasbinPolicy defines the storage format following the definition below:
 // https://casbin.org/docs/policy-storage#database-storage-format
+//
+// The values left unused by a rule are stored as empty strings, never NULL,
+// unless WithInsertIgnoreEmptyTrailing is used, so that the unique index
+// rejects a rule stored twice.
+//
+// A CasbinPolicy is serialized to JSON with the column names as keys, leaving
+// out the zero id and the unused values, see ExportJSON.
 type CasbinPolicy struct {
-	bun.BaseModel `bun:"casbin_policies,alias:cp"`
-	ID            int64  `bun:"id,pk,autoincrement"`
-	PType         string `bun:"ptype,type:varchar(100),notnull"`
-	V0            string `bun:"v0,type:varchar(100)"`
-	V1            string `bun:"v1,type:varchar(100)"`
-	V2            string `bun:"v2,type:varchar(100)"`
-	V3            string `bun:"v3,type:varchar(100)"`
-	V4            string `bun:"v4,type:varchar(100)"`
-	V5            string `bun:"v5,type:varchar(100)"`
+	bun.BaseModel `bun:"casbin_policies,alias:cp" json:"-"`
+	ID            int64  `bun:"id,pk,autoincrement" json:"id,omitempty"`
+	PType         string `bun:"ptype,type:varchar(100),notnull" json:"ptype"`
+	V0            string `bun:"v0,type:varchar(100)" json:"v0,omitempty"`
+	V1            string `bun:"v1,type:varchar(100)" json:"v1,omitempty"`
+	V2            string `bun:"v2,type:varchar(100)" json:"v2,omitempty"`
+	V3            string `bun:"v3,type:varchar(100)" json:"v3,omitempty"`
+	V4            string `bun:"v4,type:varchar(100)" json:"v4,omitempty"`
+	V5            string `bun:"v5,type:varchar(100)" json:"v5,omitempty"`
 }
 
 func (c CasbinPolicy) toSlice() []string {
@@ -30,9 +37,18 @@ func (c CasbinPolicy) filterValues() []string {
 	return nonEmptyFields(fields)
 }
 
+func (c CasbinPolicy) values() []string {
+	return []string{c.V0, c.V1, c.V2, c.V3, c.V4, c.V5}
+}
+
+// key identifies the rule stored by the policy, regardless of its id.
+func (c CasbinPolicy) key() [7]string {
+	return [7]string{c.PType, c.V0, c.V1, c.V2, c.V3, c.V4, c.V5}
+}
+
 func (c CasbinPolicy) filterValuesWithKey() map[string]string {
 	values := make(map[string]string)
-	for i, v := range []string{c.V0, c.V1, c.V2, c.V3, c.V4, c.V5} {
+	for i, v := range c.values() {
 		if v != "" {
 			values[fmt.Sprintf("v%d", i)] = v
 		}
@@ -0,0 +1,146 @@
+package casbun
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/uptrace/bun"
+)
+
+// WithPriorityColumn adds the integer column col to the policy table, and
+// loads the rules ordered by it, then by id, or by rule when WithCompositeKey
+// is used. This keeps the load order of priority-based models, such as those
+// with a deny-override effect, deterministic across restarts.
+//
+// Inserted rules take their priority from the value set by WithPriorityField,
+// or otherwise from a counter following the highest stored priority, so that
+// rules load in the order they were added. The rules inserted by a single
+// statement share the counter base and keep their order among themselves.
+//
+// The column is created with the table, so an existing table must be altered
+// to add it. NewAdapter fails if col is empty or if WithPriorityColumn is
+// combined with WithModel.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithPriorityColumn("priority"), WithPriorityField(0))
+func WithPriorityColumn(col string) CasbinBunOption {
+	return func(a *Adapter) {
+		if col == "" {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: priority column must not be empty"))
+			return
+		}
+		a.priorityColumn = col
+	}
+}
+
+// WithPriorityField takes the priority of the rules inserted with
+// WithPriorityColumn from their value at index, such as 0 for a model
+// defining p = priority, sub, obj, act, eft. Rules whose value at index is not
+// an integer, such as role assignments, take their priority from the counter
+// instead. NewAdapter fails if index is not between 0 and 5, or if
+// WithPriorityColumn is not used.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithPriorityColumn("priority"), WithPriorityField(0))
+func WithPriorityField(index int) CasbinBunOption {
+	return func(a *Adapter) {
+		if index < 0 || index >= len(valueColumns) {
+			a.optionErr = errors.Join(a.optionErr, fmt.Errorf("casbun: priority field %d is out of range", index))
+			return
+		}
+		a.priorityField = index
+	}
+}
+
+// loadOrder returns the columns ordering the rows of a load: the column set by
+// WithPriorityColumn if any, then the id, or the rule when WithCompositeKey is
+// used.
+func (a *Adapter) loadOrder() []interface{} {
+	key := []string{"id"}
+	if a.compositeKey {
+		key = append([]string{"ptype"}, valueColumns...)
+	}
+
+	order := make([]interface{}, 0, len(key)+1)
+	if a.priorityColumn != "" {
+		order = append(order, bun.Ident(a.priorityColumn))
+	}
+	for _, col := range key {
+		order = append(order, bun.Ident(col))
+	}
+	return order
+}
+
+// orderedSelect orders query by the expressions returned by loadOrder.
+func (a *Adapter) orderedSelect(query *bun.SelectQuery) *bun.SelectQuery {
+	for _, expr := range a.loadOrder() {
+		query = query.OrderExpr("?", expr)
+	}
+	return query
+}
+
+// newPriorityRows returns the rows inserting the rows held by rows, a pointer
+// to a policy row or to a slice of them, with the priority of policies in the
+// column set by WithPriorityColumn.
+func (a *Adapter) newPriorityRows(rows interface{}, policies []CasbinPolicy) interface{} {
+	src := reflect.Indirect(reflect.ValueOf(rows))
+	if src.Kind() != reflect.Slice {
+		single := reflect.MakeSlice(reflect.SliceOf(src.Type()), 1, 1)
+		single.Index(0).Set(src)
+		src = single
+	}
+
+	rowType := src.Type().Elem()
+	fields := make([]reflect.StructField, 0, rowType.NumField()+1)
+	for i := 0; i < rowType.NumField(); i++ {
+		fields = append(fields, rowType.Field(i))
+	}
+	fields = append(fields, reflect.StructField{
+		Name: "Priority",
+		Type: reflect.TypeOf((*interface{})(nil)).Elem(),
+		Tag:  reflect.StructTag(fmt.Sprintf(`bun:"%s"`, a.priorityColumn)),
+	})
+	priorityType := reflect.StructOf(fields)
+
+	dst := reflect.New(reflect.SliceOf(priorityType))
+	counter := 0
+	for i := 0; i < src.Len(); i++ {
+		row := reflect.New(priorityType).Elem()
+		for j := 0; j < rowType.NumField(); j++ {
+			row.Field(j).Set(src.Index(i).Field(j))
+		}
+
+		priority, ok := a.rulePriority(policies[i])
+		if !ok {
+			counter++
+			priority = bun.SafeQuery(
+				"(SELECT COALESCE(MAX(?), 0) FROM (SELECT ? FROM ?) AS cp) + ?",
+				bun.Ident(a.priorityColumn),
+				bun.Ident(a.priorityColumn),
+				bun.Ident(a.tableName),
+				counter,
+			)
+		}
+		row.Field(rowType.NumField()).Set(reflect.ValueOf(priority))
+
+		dst.Elem().Set(reflect.Append(dst.Elem(), row))
+	}
+	return dst.Interface()
+}
+
+// rulePriority returns the priority held by policy in the field set by
+// WithPriorityField, and whether it holds one.
+func (a *Adapter) rulePriority(policy CasbinPolicy) (interface{}, bool) {
+	if a.priorityField < 0 {
+		return nil, false
+	}
+	priority, err := strconv.ParseInt(policy.values()[a.priorityField], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return priority, true
+}
@@ -0,0 +1,151 @@
+package casbun_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+)
+
+var priorityModelStr = `
+    [request_definition]
+    r = sub, obj, act
+
+    [policy_definition]
+    p = priority, sub, obj, act, eft
+
+    [role_definition]
+    g = _, _
+
+    [policy_effect]
+    e = priority(p.eft) || deny
+
+    [matchers]
+    m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+func TestPriorityColumn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts []casbun.CasbinBunOption
+		add  [][]string
+		want [][]string
+	}{
+		{
+			name: "priority field",
+			opts: []casbun.CasbinBunOption{casbun.WithPriorityField(0)},
+			add: [][]string{
+				{"10", "alice", "data1", "read", "allow"},
+				{"1", "alice", "data1", "read", "deny"},
+				{"5", "bob", "data1", "read", "deny"},
+				{"2", "bob", "data1", "read", "allow"},
+			},
+			want: [][]string{
+				{"1", "alice", "data1", "read", "deny"},
+				{"2", "bob", "data1", "read", "allow"},
+				{"5", "bob", "data1", "read", "deny"},
+				{"10", "alice", "data1", "read", "allow"},
+			},
+		},
+		{
+			// UUIDs do not follow the insertion order, unlike the counter.
+			name: "counter",
+			opts: []casbun.CasbinBunOption{casbun.WithUUIDKey()},
+			add: [][]string{
+				{"1", "alice", "data1", "read", "deny"},
+				{"1", "alice", "data1", "read", "allow"},
+				{"1", "bob", "data1", "read", "allow"},
+				{"1", "bob", "data1", "read", "deny"},
+			},
+			want: [][]string{
+				{"1", "alice", "data1", "read", "deny"},
+				{"1", "alice", "data1", "read", "allow"},
+				{"1", "bob", "data1", "read", "allow"},
+				{"1", "bob", "data1", "read", "deny"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			opts := append([]casbun.CasbinBunOption{casbun.WithPriorityColumn("priority")}, tt.opts...)
+			adapter, err := casbun.NewAdapter(ctx, initDB(), opts...)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			for _, rule := range tt.add {
+				if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+					t.Fatalf("failed to add policy: %v", err)
+				}
+			}
+
+			for _, load := range []func(context.Context, model.Model) error{adapter.LoadPolicyCtx, adapter.LoadPolicyStream} {
+				m, err := model.NewModelFromString(priorityModelStr)
+				if err != nil {
+					t.Fatalf("unable to create model: %v", err)
+				}
+				if err := load(ctx, m); err != nil {
+					t.Fatalf("failed to load policy: %v", err)
+				}
+				if got := m["p"]["p"].Policy; !util.Array2DEquals(tt.want, got) {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := casbun.NewAdapter(context.Background(), initDB(), casbun.WithPriorityField(0)); err == nil {
+		t.Errorf("expected an error for a priority field without a priority column")
+	}
+}
+
+func TestPriorityColumnLoadCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB(),
+		casbun.WithPriorityColumn("priority"),
+		casbun.WithPriorityField(0),
+		casbun.WithLoadCache(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	for _, rule := range [][]string{
+		{"10", "alice", "data1", "read", "allow"},
+		{"1", "alice", "data1", "read", "deny"},
+		{"5", "bob", "data1", "read", "deny"},
+	} {
+		if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+	}
+
+	want := [][]string{
+		{"1", "alice", "data1", "read", "deny"},
+		{"5", "bob", "data1", "read", "deny"},
+		{"10", "alice", "data1", "read", "allow"},
+	}
+	// The first load fills the cache, the second is served from it.
+	for i := 0; i < 2; i++ {
+		m, err := model.NewModelFromString(priorityModelStr)
+		if err != nil {
+			t.Fatalf("unable to create model: %v", err)
+		}
+		if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+			t.Fatalf("failed to load policy: %v", err)
+		}
+		if got := m["p"]["p"].Policy; !util.Array2DEquals(want, got) {
+			t.Errorf("got %v on load %d, want %v", got, i+1, want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package casbun
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/uptrace/bun"
+)
+
+// WithPTypeFilter restricts the adapter to the rules of the allowed ptypes,
+// for tables shared with other tools storing rows of their own. Loads ignore
+// the rows of other ptypes, and SavePolicy and Clear neither write nor remove
+// them: the table is then emptied by a DELETE of the allowed ptypes instead of
+// being truncated. Likewise, Snapshot only returns the rows of the allowed
+// ptypes, and Restore only replaces them and fails on a policy of another
+// ptype. NewAdapter fails if no ptype is allowed.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithPTypeFilter("p", "g"))
+func WithPTypeFilter(allowed ...string) CasbinBunOption {
+	return func(a *Adapter) {
+		if len(allowed) == 0 {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: ptype filter must allow at least one ptype"))
+			return
+		}
+		a.ptypes = allowed
+	}
+}
+
+// allowedPTypes restricts a query to the rows of the ptypes allowed by
+// WithPTypeFilter, if any.
+func (a *Adapter) allowedPTypes(q bun.QueryBuilder) bun.QueryBuilder {
+	if a.ptypes == nil {
+		return q
+	}
+	return q.Where("? IN (?)", ptypeColumn, bun.In(a.ptypes))
+}
+
+// allowedPolicies returns the policies of the ptypes allowed by
+// WithPTypeFilter, if any.
+func (a *Adapter) allowedPolicies(policies []CasbinPolicy) []CasbinPolicy {
+	if a.ptypes == nil {
+		return policies
+	}
+
+	allowed := make([]CasbinPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if slices.Contains(a.ptypes, policy.PType) {
+			allowed = append(allowed, policy)
+		}
+	}
+	return allowed
+}
+
+// checkAllowedPolicies returns an error if one of policies is of a ptype not
+// allowed by WithPTypeFilter.
+func (a *Adapter) checkAllowedPolicies(policies []CasbinPolicy) error {
+	if a.ptypes == nil {
+		return nil
+	}
+	for _, policy := range policies {
+		if !slices.Contains(a.ptypes, policy.PType) {
+			return fmt.Errorf("casbun: ptype %s is not allowed by the ptype filter", policy.PType)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package casbun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestPTypeFilter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPTypeFilter("p", "g"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	foreign := &casbun.CasbinPolicy{PType: "x", V0: "job", V1: "nightly"}
+	if _, err := db.NewInsert().Model(foreign).Exec(ctx); err != nil {
+		t.Fatalf("unable to insert foreign row: %v", err)
+	}
+
+	m, err := model.NewModelFromString(modelStr)
+	if err != nil {
+		t.Fatalf("unable to create model: %v", err)
+	}
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("unable to create enforcer: %v", err)
+	}
+	if got, _ := e.GetPolicy(); len(got) != 1 {
+		t.Errorf("got policy %v, want only the rule of alice", got)
+	}
+
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("failed to save policy: %v", err)
+	}
+	if err := adapter.Clear(ctx); err != nil {
+		t.Fatalf("failed to clear policy: %v", err)
+	}
+	count, err := db.NewSelect().
+		Model((*casbun.CasbinPolicy)(nil)).
+		Where("ptype = 'x'").
+		Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count foreign rows: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d foreign rows, want 1", count)
+	}
+}
+
+func TestPTypeFilterRestore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPTypeFilter("p", "g"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	foreign := &casbun.CasbinPolicy{PType: "x", V0: "job", V1: "nightly"}
+	if _, err := db.NewInsert().Model(foreign).Exec(ctx); err != nil {
+		t.Fatalf("unable to insert foreign row: %v", err)
+	}
+
+	snapshot, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if len(snapshot) != 1 || snapshot[0].PType != "p" {
+		t.Errorf("got snapshot %+v, want only the rule of alice", snapshot)
+	}
+
+	if err := adapter.Restore(ctx, []casbun.CasbinPolicy{{PType: "p", V0: "bob", V1: "data2", V2: "write"}}); err != nil {
+		t.Fatalf("unable to restore: %v", err)
+	}
+	if err := adapter.Restore(ctx, []casbun.CasbinPolicy{{PType: "x", V0: "job", V1: "hourly"}}); err == nil {
+		t.Errorf("expected an error restoring a policy of a filtered out ptype")
+	}
+
+	var rows []casbun.CasbinPolicy
+	if err := db.NewSelect().Model(&rows).Order("ptype").Scan(ctx); err != nil {
+		t.Fatalf("unable to read rows: %v", err)
+	}
+	if len(rows) != 2 || rows[0].V0 != "bob" || rows[1].V1 != "nightly" {
+		t.Errorf("got rows %+v, want the restored rule of bob and the foreign row", rows)
+	}
+}
@@ -0,0 +1,106 @@
+package casbun
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// WithPTypeTable stores the rules of the ptypes starting with each prefix of
+// tables in the table it maps to, such as {"g": "casbin_groups"} to keep the
+// g, g2 and g3 rules apart from the p rules, for locality with large and
+// unevenly-sized sections. When several prefixes match a ptype, the longest
+// one wins, and the rules of the other ptypes stay in the table set by
+// WithTableName. Each table is created with its own indexes.
+//
+// Loads merge the rules of every table, while the operations on the rules of
+// a ptype, such as AddPolicy or RemoveFilteredPolicy, run on its table only.
+// SavePolicy and Clear replace the rules of every table, one table at a time,
+// and Snapshot and Restore, as ExportJSON and ImportJSON, cover every table.
+// The other operations, such as Stats or ValidateSchema, apply to the table
+// set by WithTableName only. NewAdapter fails if a prefix or a table
+// is empty, or if WithPTypeTable is combined with WithWriteBuffer.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithPTypeTable(map[string]string{"g": "casbin_groups"}))
+func WithPTypeTable(tables map[string]string) CasbinBunOption {
+	return func(a *Adapter) {
+		for prefix, table := range tables {
+			if prefix == "" || table == "" {
+				a.optionErr = errors.Join(a.optionErr, errors.New("casbun: ptype table prefixes and names must not be empty"))
+				return
+			}
+		}
+		a.ptypeTables = tables
+	}
+}
+
+// route returns the adapter running the operations on the rules of ptype: a
+// copy of a using the table set for ptype by WithPTypeTable, or a itself when
+// no table is set for ptype.
+func (a *Adapter) route(ptype string) *Adapter {
+	prefix := ""
+	for p := range a.ptypeTables {
+		if strings.HasPrefix(ptype, p) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return a
+	}
+	return a.routeTo(a.ptypeTables[prefix])
+}
+
+// routes returns the adapters running the operations on every table: a itself
+// without WithPTypeTable, and otherwise a copy of a per table, the table set
+// by WithTableName first.
+func (a *Adapter) routes() []*Adapter {
+	if a.ptypeTables == nil {
+		return []*Adapter{a}
+	}
+
+	tables := make([]string, 0, len(a.ptypeTables))
+	for _, table := range a.ptypeTables {
+		if table != a.tableName && !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+
+	routes := []*Adapter{a.routeTo(a.tableName)}
+	for _, table := range tables {
+		routes = append(routes, a.routeTo(table))
+	}
+	return routes
+}
+
+// routeTo returns a copy of a running its operations on table, without
+// routing them any further.
+func (a *Adapter) routeTo(table string) *Adapter {
+	routed := *a
+	routed.tableName = table
+	routed.ptypeTables = nil
+	return &routed
+}
+
+// routePolicies returns policies grouped by the adapter of their table, in the
+// order of routes.
+func (a *Adapter) routePolicies(policies []CasbinPolicy) ([]*Adapter, [][]CasbinPolicy) {
+	routes := a.routes()
+	grouped := make([][]CasbinPolicy, len(routes))
+	for i := range grouped {
+		grouped[i] = make([]CasbinPolicy, 0)
+	}
+	for _, policy := range policies {
+		table := a.route(policy.PType).tableName
+		for i, r := range routes {
+			if r.tableName == table {
+				grouped[i] = append(grouped[i], policy)
+				break
+			}
+		}
+	}
+	return routes, grouped
+}
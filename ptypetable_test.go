@@ -0,0 +1,78 @@
+package casbun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestPTypeTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPTypeTable(map[string]string{"g": "casbin_groups"}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	if _, err := e.AddPolicy("admin", "data1", "read"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if _, err := e.AddGroupingPolicy("alice", "admin"); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	if _, err := e.AddGroupingPolicy("bob", "admin"); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	if _, err := e.RemoveGroupingPolicy("bob", "admin"); err != nil {
+		t.Fatalf("failed to remove grouping policy: %v", err)
+	}
+
+	countRows := func(table, ptype string) int {
+		t.Helper()
+
+		var count int
+		if err := db.NewSelect().Table(table).ColumnExpr("COUNT(*)").Where("ptype = ?", ptype).Scan(ctx, &count); err != nil {
+			t.Fatalf("failed to count rows of %s: %v", table, err)
+		}
+		return count
+	}
+	wantRows := func() {
+		t.Helper()
+
+		if got := countRows("casbin_policies", "p"); got != 1 {
+			t.Errorf("got %d p rows in casbin_policies, want 1", got)
+		}
+		if got := countRows("casbin_policies", "g"); got != 0 {
+			t.Errorf("got %d g rows in casbin_policies, want 0", got)
+		}
+		if got := countRows("casbin_groups", "g"); got != 1 {
+			t.Errorf("got %d g rows in casbin_groups, want 1", got)
+		}
+	}
+	wantRows()
+
+	// The load merges the rules of both tables.
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{{"admin", "data1", "read"}})
+	if ok, _ := e.Enforce("alice", "data1", "read"); !ok {
+		t.Error("alice is not granted the role stored in casbin_groups")
+	}
+
+	// A save keeps the rules of each ptype in their table.
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+	wantRows()
+}
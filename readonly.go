@@ -0,0 +1,58 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// WithReadOnlyLoads runs the loads of the policy, such as LoadPolicy and
+// LoadFilteredPolicy, in a read-only transaction on the read database. The
+// tables set by WithPTypeTable are then read consistently, and the database
+// may route the loads to a replica and rejects any write made by mistake
+// during a load. SQLite has no read-only transactions, so the connection is
+// made query-only for the duration of the load instead. Loads called with a
+// context carrying a transaction run on it, as usual.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithReadDB(replica), WithReadOnlyLoads())
+func WithReadOnlyLoads() CasbinBunOption {
+	return func(a *Adapter) {
+		a.readOnlyLoads = true
+	}
+}
+
+// readOnlyLoad runs load in a read-only transaction carried by its context if
+// WithReadOnlyLoads is used, and on ctx otherwise.
+func (a *Adapter) readOnlyLoad(ctx context.Context, load func(ctx context.Context) error) error {
+	if _, ok := a.txFor(ctx); ok || !a.readOnlyLoads {
+		return load(ctx)
+	}
+
+	opts := a.txOptions()
+	opts.ReadOnly = true
+	db := a.reader(ctx)
+	if db.Dialect().Name() != dialect.SQLite {
+		return db.RunInTx(ctx, opts, func(ctx context.Context, tx bun.Tx) error {
+			return load(WithTx(ctx, tx))
+		})
+	}
+
+	// The query-only setting outlives the transaction on its pooled
+	// connection, so it must be reset even if ctx is cancelled, which would
+	// otherwise roll the transaction back and release the connection first.
+	// The statements of load still run with ctx.
+	return db.RunInTx(context.WithoutCancel(ctx), opts, func(txCtx context.Context, tx bun.Tx) (err error) {
+		if _, err := tx.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+			return err
+		}
+		defer func() {
+			_, resetErr := tx.ExecContext(txCtx, "PRAGMA query_only = OFF")
+			err = errors.Join(err, resetErr)
+		}()
+		return load(WithTx(ctx, tx))
+	})
+}
@@ -0,0 +1,79 @@
+package casbun
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+func TestReadOnlyLoads(t *testing.T) {
+	ctx := context.Background()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "file::memory:?mode=memory")
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	db := bun.NewDB(sqldb, sqlitedialect.New())
+	adapter, err := NewAdapter(ctx, db, WithReadOnlyLoads())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	m, err := model.NewModelFromString(`
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = r.sub == p.sub && r.obj == p.obj && r.act == p.act
+`)
+	if err != nil {
+		t.Fatalf("unable to create model: %v", err)
+	}
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	if ok, _ := m.HasPolicy("p", "p", []string{"alice", "data1", "read"}); !ok {
+		t.Errorf("the loaded model lacks the stored rule")
+	}
+
+	// A write made during a load is rejected.
+	err = adapter.readOnlyLoad(ctx, func(ctx context.Context) error {
+		return adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "readonly") {
+		t.Errorf("got error %v, want a read-only error for a write during a load", err)
+	}
+
+	// The connection accepts writes again after the load.
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy after the load: %v", err)
+	}
+
+	// Nor does a load cancelled midway leave the connection query-only.
+	cancelled, cancel := context.WithCancel(ctx)
+	err = adapter.readOnlyLoad(cancelled, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if err == nil {
+		t.Errorf("expected an error for a cancelled load")
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"carol", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy after the cancelled load: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package casbun
+
+import (
+	"context"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/uptrace/bun"
+)
+
+// Reconcile makes the stored policy match model like SavePolicy with
+// SaveUpsert, and reports the changes it made: the rules of model that were
+// not stored, and the stored rules missing from model. Each reported rule is
+// prefixed with its ptype, such as ["p", "alice", "data1", "read"]. The
+// changes are applied in a single transaction, and nothing is reported if it
+// fails. Rules stored with the same values keep their row, id and metadata.
+// With WithPTypeTable, the rules of each ptype are compared with the table
+// storing them.
+//
+// Example:
+//
+//	added, removed, err := adapter.Reconcile(ctx, enforcer.GetModel())
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("reconciled policy: added %v, removed %v", added, removed)
+func (a *Adapter) Reconcile(ctx context.Context, model model.Model) (added, removed [][]string, err error) {
+	defer wrapOpError("reconcile", "", &err)
+	defer a.observe("reconcile")()
+
+	policies := a.allowedPolicies(modelPolicies(model))
+	if len(policies) == 0 && a.refuseEmptySave {
+		return nil, nil, ErrEmptySave
+	}
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		added, removed = nil, nil
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				routes, grouped := a.routePolicies(policies)
+				for i, r := range routes {
+					var stored []CasbinPolicy
+					if err := r.newSelect(ctx, tx, &stored).
+						ApplyQueryBuilder(r.allowedPTypes).
+						Scan(ctx); err != nil {
+						return err
+					}
+
+					missing, stale := diffPolicies(stored, grouped[i])
+					// Stale rules are deleted first, as they may hold the unique
+					// columns of a new rule when WithUniqueColumns is used.
+					if err := r.deleteStaleRecords(ctx, tx, stale); err != nil {
+						return err
+					}
+					for start := 0; start < len(missing); start += insertBatchSize {
+						batch := missing[start:min(start+insertBatchSize, len(missing))]
+						if _, err := r.newInsert(ctx, tx, &batch).Exec(ctx); err != nil {
+							return insertError(err)
+						}
+					}
+
+					for _, policy := range missing {
+						added = append(added, policy.toSlice())
+					}
+					for _, policy := range stale {
+						removed = append(removed, policy.toSlice())
+					}
+				}
+				return nil
+			},
+		)
+	})
+	if err := a.notify(ctx, err, PolicyEvent{Op: EventSave}); err != nil {
+		return nil, nil, err
+	}
+	return added, removed, nil
+}
+
+// diffPolicies returns the policies missing from stored, and the stored
+// policies missing from policies.
+func diffPolicies(stored, policies []CasbinPolicy) (missing, stale []CasbinPolicy) {
+	keys := make(map[[7]string]struct{}, len(stored))
+	for _, policy := range stored {
+		keys[policy.key()] = struct{}{}
+	}
+	keep := make(map[[7]string]struct{}, len(policies))
+	for _, policy := range policies {
+		if _, ok := keep[policy.key()]; ok {
+			continue
+		}
+		keep[policy.key()] = struct{}{}
+		if _, ok := keys[policy.key()]; !ok {
+			missing = append(missing, policy)
+		}
+	}
+
+	for _, policy := range stored {
+		if _, ok := keep[policy.key()]; !ok {
+			stale = append(stale, policy)
+		}
+	}
+	return missing, stale
+}
@@ -0,0 +1,111 @@
+package casbun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestReconcile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	m, err := model.NewModelFromString(modelStr)
+	if err != nil {
+		t.Fatalf("unable to create model: %v", err)
+	}
+	if err := m.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy to model: %v", err)
+	}
+	if err := m.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy to model: %v", err)
+	}
+
+	added, removed, err := adapter.Reconcile(ctx, m)
+	if err != nil {
+		t.Fatalf("failed to reconcile policy: %v", err)
+	}
+	if want := [][]string{{"g", "alice", "admin"}}; !util.Array2DEquals(added, want) {
+		t.Errorf("got added rules %v, want %v", added, want)
+	}
+	if want := [][]string{{"p", "bob", "data2", "write"}}; !util.Array2DEquals(removed, want) {
+		t.Errorf("got removed rules %v, want %v", removed, want)
+	}
+
+	rules, err := adapter.LoadPolicyArray(ctx)
+	if err != nil {
+		t.Fatalf("failed to load policies: %v", err)
+	}
+	if want := [][]string{{"alice", "data1", "read"}}; !util.Array2DEquals(rules["p"], want) {
+		t.Errorf("got stored p rules %v, want %v", rules["p"], want)
+	}
+	if want := [][]string{{"alice", "admin"}}; !util.Array2DEquals(rules["g"], want) {
+		t.Errorf("got stored g rules %v, want %v", rules["g"], want)
+	}
+
+	added, removed, err = adapter.Reconcile(ctx, m)
+	if err != nil {
+		t.Fatalf("failed to reconcile policy again: %v", err)
+	}
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("got added %v and removed %v on a reconciled policy, want none", added, removed)
+	}
+}
+
+func TestReconcilePTypeTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPTypeTable(map[string]string{"g": "casbin_groups"}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "g", "g", [][]string{{"alice", "admin"}, {"bob", "admin"}}); err != nil {
+		t.Fatalf("failed to add grouping policies: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	if _, err := m.RemovePolicy("g", "g", []string{"bob", "admin"}); err != nil {
+		t.Fatalf("failed to remove grouping policy from model: %v", err)
+	}
+
+	added, removed, err := adapter.Reconcile(ctx, m)
+	if err != nil {
+		t.Fatalf("failed to reconcile policy: %v", err)
+	}
+	if len(added) != 0 {
+		t.Errorf("got added rules %v, want none", added)
+	}
+	if want := [][]string{{"g", "bob", "admin"}}; !util.Array2DEquals(removed, want) {
+		t.Errorf("got removed rules %v, want %v", removed, want)
+	}
+
+	var policies, groups int
+	if err := db.NewRaw("SELECT count(*) FROM casbin_policies").Scan(ctx, &policies); err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	if err := db.NewRaw("SELECT count(*) FROM casbin_groups").Scan(ctx, &groups); err != nil {
+		t.Fatalf("unable to count rows: %v", err)
+	}
+	if policies != 1 || groups != 1 {
+		t.Errorf("got %d rows in casbin_policies and %d in casbin_groups, want 1 and 1", policies, groups)
+	}
+}
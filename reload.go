@@ -0,0 +1,73 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PolicyLoader reloads the policy of an enforcer from its adapter, such as
+// casbin.SyncedEnforcer.
+type PolicyLoader interface {
+	LoadPolicy() error
+}
+
+// StartAutoReload calls LoadPolicy on loader, typically the enforcer using the
+// adapter, each interval until ctx is cancelled or the returned stop function
+// is called. This keeps the policy in sync with a table written by other
+// services when no Watcher is available. The enforcer rebuilds its role links
+// on each reload, and a failed reload keeps the previous policy and is retried
+// on the next tick. The reloads are counted as the auto_reload operation by
+// WithMetrics, and their errors are passed to the handler set by
+// WithBackgroundErrorHandler. As the reloads run in the background, use an
+// enforcer safe for concurrent use, such as casbin.SyncedEnforcer. stop waits
+// for a running reload to finish. It fails if interval is not positive.
+//
+// Example:
+//
+//	stop, err := adapter.StartAutoReload(ctx, enforcer, 30*time.Second)
+//	if err != nil {
+//	    return err
+//	}
+//	defer stop()
+func (a *Adapter) StartAutoReload(ctx context.Context, loader PolicyLoader, interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, errors.New("casbun: auto reload interval must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ticker := time.NewTicker(interval)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.reload(loader)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}, nil
+}
+
+// reload calls LoadPolicy on loader once for StartAutoReload, reporting its
+// error to the handler set by WithBackgroundErrorHandler.
+func (a *Adapter) reload(loader PolicyLoader) {
+	defer a.observe("auto_reload")()
+
+	if err := loader.LoadPolicy(); err != nil {
+		a.reportError(fmt.Errorf("casbun: auto reload: %w", err))
+	}
+}
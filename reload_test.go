@@ -0,0 +1,116 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestStartAutoReload(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"admin", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewSyncedEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	stop, err := adapter.StartAutoReload(ctx, e, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unable to start auto reload: %v", err)
+	}
+	defer stop()
+
+	// Another service writes to the table directly.
+	external := &casbun.CasbinPolicy{PType: "g", V0: "alice", V1: "admin"}
+	if _, err := db.NewInsert().Model(external).ModelTableExpr("casbin_policies").Exec(ctx); err != nil {
+		t.Fatalf("failed to insert external policy: %v", err)
+	}
+
+	// The reloaded grouping rule takes effect through the role links.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ok, err := e.Enforce("alice", "data1", "read")
+		if err != nil {
+			t.Fatalf("unable to enforce: %v", err)
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the external grouping policy was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := adapter.StartAutoReload(ctx, e, 0); err == nil {
+		t.Error("expected an error for a zero interval")
+	}
+}
+
+// failingLoader is a PolicyLoader whose reloads fail with err.
+type failingLoader struct {
+	err error
+}
+
+func (l failingLoader) LoadPolicy() error {
+	return l.err
+}
+
+func TestStartAutoReloadError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	metrics := &recordingMetrics{latencies: make(map[string]int)}
+	errs := make(chan error, 1)
+	adapter, err := casbun.NewAdapter(ctx, initDB(),
+		casbun.WithMetrics(metrics),
+		casbun.WithBackgroundErrorHandler(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	errReload := errors.New("reload failed")
+	stop, err := adapter.StartAutoReload(ctx, failingLoader{err: errReload}, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unable to start auto reload: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, errReload) {
+			t.Errorf("got error %v, want %v", err, errReload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the reload error was not reported")
+	}
+	stop()
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if !slices.Contains(metrics.ops, "auto_reload") {
+		t.Errorf("got operations %v, want auto_reload", metrics.ops)
+	}
+}
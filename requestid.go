@@ -0,0 +1,32 @@
+package casbun
+
+import "context"
+
+// WithRequestID tags the statements issued by the adapter with the request id
+// returned by requestID for the context of the operation, as a SQL comment
+// such as /* request_id=42 */, to correlate database logs with distributed
+// traces. Statements are not tagged when requestID returns an empty id.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithRequestID(func(ctx context.Context) string {
+//	    return middleware.GetReqID(ctx)
+//	}))
+func WithRequestID(requestID func(ctx context.Context) string) CasbinBunOption {
+	return func(a *Adapter) {
+		a.requestID = requestID
+	}
+}
+
+// queryComment returns the comment tagging the statements run with ctx, which
+// is empty unless WithRequestID is used. Bun escapes the comment delimiters
+// found in the id.
+func (a *Adapter) queryComment(ctx context.Context) string {
+	if a.requestID == nil {
+		return ""
+	}
+	if id := a.requestID(ctx); id != "" {
+		return "request_id=" + id
+	}
+	return ""
+}
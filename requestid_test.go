@@ -0,0 +1,81 @@
+package casbun_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+)
+
+type requestIDKey struct{}
+
+// querySpy records the queries run on a database.
+type querySpy struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (s *querySpy) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (s *querySpy) AfterQuery(_ context.Context, event *bun.QueryEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, event.Query)
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	db := initDB()
+	spy := &querySpy{}
+	db.AddQueryHook(spy)
+
+	adapter, err := casbun.NewAdapter(context.Background(), db, casbun.WithRequestID(func(ctx context.Context) string {
+		id, _ := ctx.Value(requestIDKey{}).(string)
+		return id
+	}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "req-42")
+	spy.queries = nil
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	m, err := model.NewModelFromString(modelStr)
+	if err != nil {
+		t.Fatalf("unable to create model: %v", err)
+	}
+	if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	if err := adapter.RemovePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to remove policy: %v", err)
+	}
+
+	if len(spy.queries) != 3 {
+		t.Fatalf("got queries %q, want 3", spy.queries)
+	}
+	for _, query := range spy.queries {
+		if !strings.HasPrefix(query, "/* request_id=req-42 */ ") {
+			t.Errorf("got query %q, want it tagged with the request id", query)
+		}
+	}
+
+	spy.queries = nil
+	if err := adapter.AddPolicyCtx(context.Background(), "p", "p", []string{"bob", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	for _, query := range spy.queries {
+		if strings.Contains(query, "request_id") {
+			t.Errorf("got query %q tagged without a request id", query)
+		}
+	}
+}
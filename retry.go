@@ -0,0 +1,107 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WithRetry retries the operations that modify the stored policy when they
+// fail with a transient error, such as a serialization failure on PostgreSQL
+// or a busy database on SQLite. An operation is attempted at most maxAttempts
+// times, and backoff returns the delay before each retry, starting with
+// attempt 1. Any other error is returned immediately.
+//
+// Operations running on a transaction carried by the context are never
+// retried, as the failure aborts the caller's transaction.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithRetry(3, func(attempt int) time.Duration {
+//	    return time.Duration(attempt) * 50 * time.Millisecond
+//	}))
+func WithRetry(maxAttempts int, backoff func(attempt int) time.Duration) CasbinBunOption {
+	return func(a *Adapter) {
+		a.maxAttempts = maxAttempts
+		a.backoff = backoff
+	}
+}
+
+// WithAutoRecreateTable recreates the policy table when an operation
+// modifying the stored policy fails because the table does not exist, e.g.
+// after it was dropped by mistake, and runs the operation once more. Loads
+// still fail with ErrTableMissing, as a recreated table holds no rule.
+// Operations running on a transaction carried by the context are not
+// retried.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithAutoRecreateTable())
+func WithAutoRecreateTable() CasbinBunOption {
+	return func(a *Adapter) {
+		a.autoRecreateTable = true
+	}
+}
+
+// retry runs op like retryTransient, after flushing the rules buffered by
+// WithWriteBuffer so that op observes them. When op fails because the table is
+// missing, it recreates the table and runs op once more if
+// WithAutoRecreateTable is used, and returns an error wrapping
+// ErrTableMissing otherwise.
+func (a *Adapter) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	if err := a.flushWrites(ctx); err != nil {
+		return err
+	}
+
+	err := a.retryTransient(ctx, op)
+	if err == nil || !isMissingTable(err) {
+		return err
+	}
+	if _, ok := a.txFor(ctx); ok || !a.autoRecreateTable {
+		return tableError(err)
+	}
+
+	if err := a.createTable(ctx); err != nil {
+		return err
+	}
+	return tableError(a.retryTransient(ctx, op))
+}
+
+// retryTransient runs op, running it again while it fails with a transient
+// error and the attempts configured with WithRetry are not exhausted.
+func (a *Adapter) retryTransient(ctx context.Context, op func(ctx context.Context) error) error {
+	if _, ok := a.txFor(ctx); ok || a.maxAttempts <= 1 {
+		return op(ctx)
+	}
+	return retryAttempts(ctx, a.maxAttempts, a.backoff, op)
+}
+
+// retryAttempts runs op at most maxAttempts times while it fails with a
+// transient error, waiting for the delay returned by backoff before each
+// retry.
+func retryAttempts(
+	ctx context.Context,
+	maxAttempts int,
+	backoff func(attempt int) time.Duration,
+	op func(ctx context.Context) error,
+) error {
+	for attempt := 1; ; attempt++ {
+		err := op(ctx)
+		if err == nil || attempt >= maxAttempts || !isTransient(err) {
+			return err
+		}
+
+		var delay time.Duration
+		if backoff != nil {
+			delay = backoff(attempt)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package casbun_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+// flakyConnector opens sqlite connections whose statements fail with err
+// while failures is positive.
+type flakyConnector struct {
+	driver driver.Driver
+	dsn    string
+
+	err      error
+	failures atomic.Int32
+	calls    atomic.Int32
+}
+
+func (c *flakyConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyConn{Conn: conn, connector: c}, nil
+}
+
+func (c *flakyConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// fail reports the error the next statement should fail with, if any.
+func (c *flakyConnector) fail() error {
+	c.calls.Add(1)
+	if c.failures.Add(-1) >= 0 {
+		return c.err
+	}
+	return nil
+}
+
+type flakyConn struct {
+	driver.Conn
+	connector *flakyConnector
+}
+
+func (c *flakyConn) ExecContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Result, error) {
+	if err := c.connector.fail(); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *flakyConn) QueryContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Rows, error) {
+	if err := c.connector.fail(); err != nil {
+		return nil, err
+	}
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func initFlakyDB(t *testing.T, err error) (*bun.DB, *flakyConnector) {
+	t.Helper()
+
+	sqldb, openErr := sql.Open(sqliteshim.ShimName, "")
+	if openErr != nil {
+		t.Fatalf("unable to open database: %v", openErr)
+	}
+	connector := &flakyConnector{
+		driver: sqldb.Driver(),
+		dsn:    "file:" + filepath.Join(t.TempDir(), "casbin.db") + "?_pragma=busy_timeout(5000)",
+		err:    err,
+	}
+	_ = sqldb.Close()
+
+	db := bun.NewDB(sql.OpenDB(connector), sqlitedialect.New())
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db, connector
+}
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		err       error
+		wantErr   bool
+		wantCalls int32
+	}{
+		{
+			name:      "transient",
+			err:       errors.New("database is locked (5) (SQLITE_BUSY)"),
+			wantCalls: 3,
+		},
+		{
+			name:      "not transient",
+			err:       errors.New("no such table: casbin_policies"),
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			db, connector := initFlakyDB(t, tt.err)
+
+			var backoffs []int
+			adapter, err := casbun.NewAdapter(ctx, db, casbun.WithRetry(5, func(attempt int) time.Duration {
+				backoffs = append(backoffs, attempt)
+				return time.Millisecond
+			}))
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			connector.failures.Store(2)
+			connector.calls.Store(0)
+			err = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			if got := connector.calls.Load(); got != tt.wantCalls {
+				t.Errorf("got %d statements, want %d", got, tt.wantCalls)
+			}
+			if got := len(backoffs); got != int(tt.wantCalls)-1 {
+				t.Errorf("got %d backoffs, want %d", got, tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestAutoRecreateTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		opts     []casbun.CasbinBunOption
+		recreate bool
+	}{
+		{name: "default"},
+		{name: "auto recreate", opts: []casbun.CasbinBunOption{casbun.WithAutoRecreateTable()}, recreate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			db := initFileDB(t)
+			adapter, err := casbun.NewAdapter(ctx, db, tt.opts...)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+				t.Fatalf("failed to add policy: %v", err)
+			}
+
+			if _, err := db.NewDropTable().Table("casbin_policies").Exec(ctx); err != nil {
+				t.Fatalf("unable to drop table: %v", err)
+			}
+
+			err = adapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data1", "read"})
+			if tt.recreate {
+				if err != nil {
+					t.Fatalf("failed to add policy after the table was dropped: %v", err)
+				}
+			} else if !errors.Is(err, casbun.ErrTableMissing) {
+				t.Errorf("got error %v, want %v", err, casbun.ErrTableMissing)
+			}
+
+			m, err := model.NewModelFromString(modelStr)
+			if err != nil {
+				t.Fatalf("unable to create model: %v", err)
+			}
+			err = adapter.LoadPolicyCtx(ctx, m)
+			if tt.recreate {
+				if err != nil {
+					t.Fatalf("failed to load policy: %v", err)
+				}
+				if got := m["p"]["p"].Policy; len(got) != 1 {
+					t.Errorf("got policy %v, want the rule of bob only", got)
+				}
+			} else if !errors.Is(err, casbun.ErrTableMissing) {
+				t.Errorf("got error %v, want %v", err, casbun.ErrTableMissing)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package casbun
+
+// RowValidator checks a rule of ptype before it is written, see
+// WithRowValidator.
+type RowValidator func(ptype string, rule []string) error
+
+// WithRowValidator calls validate for each rule before it is added or
+// before an update writes it, to reject rules such as ones holding
+// characters that would break a CSV export. The operation is aborted without
+// writing anything if validate returns an error, which is returned to the
+// caller. Unlike the hook set by WithBeforeMutation, validate only sees the
+// rules being written, and is not called for removals.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithRowValidator(
+//	    func(ptype string, rule []string) error {
+//	        for _, v := range rule {
+//	            if strings.ContainsRune(v, ',') {
+//	                return fmt.Errorf("value %q of %s holds a comma", v, ptype)
+//	            }
+//	        }
+//	        return nil
+//	    },
+//	))
+func WithRowValidator(validate RowValidator) CasbinBunOption {
+	return func(a *Adapter) {
+		a.rowValidator = validate
+	}
+}
+
+// validateRules calls the validator set by WithRowValidator for each of
+// rules, and stops at the first error.
+func (a *Adapter) validateRules(ptype string, rules [][]string) error {
+	if a.rowValidator == nil {
+		return nil
+	}
+	for _, rule := range rules {
+		if err := a.rowValidator(ptype, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestRowValidator(t *testing.T) {
+	t.Parallel()
+
+	errComma := errors.New("rule holds a comma")
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db,
+		casbun.WithRowValidator(func(ptype string, rule []string) error {
+			for _, v := range rule {
+				if strings.ContainsRune(v, ',') {
+					return errComma
+				}
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1,data2", "read"}); !errors.Is(err, errComma) {
+		t.Errorf("got error %v, want %v", err, errComma)
+	}
+	count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("got %d rules after the rejected add, want 0", count)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	err = adapter.UpdatePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}, []string{"alice", "data1,data2", "read"})
+	if !errors.Is(err, errComma) {
+		t.Errorf("got error %v, want %v", err, errComma)
+	}
+	count, err = db.NewSelect().
+		Model((*casbun.CasbinPolicy)(nil)).
+		Where("v1 = ?", "data1").
+		Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d unchanged rules after the rejected update, want 1", count)
+	}
+}
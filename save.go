@@ -0,0 +1,248 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/uptrace/bun"
+)
+
+// SaveStrategy defines how SavePolicy replaces the stored policy.
+type SaveStrategy int
+
+const (
+	// SaveTruncate empties the table and inserts every rule of the model.
+	// Readers may observe an empty or partial policy while the save runs.
+	// This is the default strategy.
+	SaveTruncate SaveStrategy = iota
+	// SaveUpsert inserts the rules of the model that are not stored yet and
+	// deletes the stored rules missing from the model, in a single transaction.
+	// Readers observe either the old or the new policy, never an empty one.
+	// It is supported on PostgreSQL, SQLite and MySQL.
+	SaveUpsert
+	// SaveSwap inserts every rule of the model into a staging table, then
+	// drops the policy table and renames the staging table in its place, in a
+	// single transaction. Readers observe either the old or the new policy,
+	// never an empty one. It is only supported on SQLite, and falls back to
+	// SaveUpsert on other dialects: PostgreSQL fails the readers waiting on
+	// the dropped table, MySQL commits each DDL statement on its own, and
+	// MSSQL has no RENAME TO. It also falls back to SaveUpsert when
+	// WithPTypeFilter is used, as the swap would drop the rows of other
+	// ptypes. The ids of the rules are not preserved.
+	SaveSwap
+)
+
+// WithSaveStrategy sets the strategy used by SavePolicy to replace the stored
+// policy. The default is SaveTruncate.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithSaveStrategy(SaveUpsert))
+func WithSaveStrategy(strategy SaveStrategy) CasbinBunOption {
+	return func(a *Adapter) {
+		a.saveStrategy = strategy
+	}
+}
+
+// WithRefuseEmptySave makes SavePolicy fail with ErrEmptySave instead of
+// emptying the table when the model holds no rule, such as a model missing
+// its p and g sections. This protects the stored policy from a model built by
+// mistake. Clear still empties the table on purpose.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithRefuseEmptySave())
+func WithRefuseEmptySave() CasbinBunOption {
+	return func(a *Adapter) {
+		a.refuseEmptySave = true
+	}
+}
+
+// SaveSection replaces the stored rules of ptypes, such as g and g2, with the
+// rules of model of these ptypes, in a single transaction, leaving the rules
+// of other ptypes untouched. It suits enforcers managing a subset of the
+// policy owned by several systems, where SavePolicy would replace everything.
+// It fails if no ptype is given.
+//
+// Example:
+//
+//	err := adapter.SaveSection(ctx, enforcer.GetModel(), "g")
+func (a *Adapter) SaveSection(ctx context.Context, model model.Model, ptypes ...string) (err error) {
+	defer wrapOpError("save_section", "", &err)
+	defer a.observe("save_section")()
+
+	if len(ptypes) == 0 {
+		return errors.New("casbun: no ptype to save")
+	}
+
+	policies := make([]CasbinPolicy, 0)
+	for _, policy := range a.allowedPolicies(modelPolicies(model)) {
+		if slices.Contains(ptypes, policy.PType) {
+			policies = append(policies, policy)
+		}
+	}
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				routes, grouped := a.routePolicies(policies)
+				for i, r := range routes {
+					if _, err := r.newDelete(ctx, tx).
+						Where("? IN (?)", ptypeColumn, bun.In(ptypes)).
+						ApplyQueryBuilder(r.allowedPTypes).
+						Exec(ctx); err != nil {
+						return err
+					}
+
+					policies := grouped[i]
+					for start := 0; start < len(policies); start += insertBatchSize {
+						batch := policies[start:min(start+insertBatchSize, len(policies))]
+						if _, err := r.newInsert(ctx, tx, &batch).Exec(ctx); err != nil {
+							return insertError(err)
+						}
+					}
+				}
+				return nil
+			},
+		)
+	})
+	return a.notify(ctx, err, PolicyEvent{Op: EventSave})
+}
+
+// newInsertIgnore returns a query inserting policies, skipping the rules that
+// are already stored. It is supported on PostgreSQL, SQLite and MySQL.
+func (a *Adapter) newInsertIgnore(ctx context.Context, db bun.IDB, policies *[]CasbinPolicy) (*bun.InsertQuery, error) {
+	query := a.newInsert(ctx, db, policies).Returning("NULL")
+	switch features := dialectOf(db.Dialect().Name()); {
+	case features.onConflictDoNothing:
+		// Without a conflict target, any unique index skips the rule, such as
+		// one restricted by WithUniqueColumns.
+		return query.On("CONFLICT DO NOTHING"), nil
+	case features.insertIgnore:
+		return query.Ignore(), nil
+	default:
+		return nil, fmt.Errorf("casbun: skipping existing rules is not supported on %s", features.name)
+	}
+}
+
+// swapPolicyRecords replaces the stored policy with policies by swapping the
+// policy table with a staging table holding them.
+func (a *Adapter) swapPolicyRecords(ctx context.Context, policies []CasbinPolicy) error {
+	if !a.dialect().renameSwap || a.ptypes != nil {
+		return a.upsertPolicyRecords(ctx, policies)
+	}
+
+	_, table := a.splitTableName()
+	staging := *a
+	staging.tableName = a.tableName + "_staging"
+
+	return a.conn(ctx).RunInTx(
+		ctx,
+		a.txOptions(),
+		func(ctx context.Context, tx bun.Tx) error {
+			// A staging table left by a crashed save is discarded.
+			if _, err := tx.NewDropTable().
+				Comment(a.queryComment(ctx)).
+				TableExpr("?", bun.Ident(staging.tableName)).
+				IfExists().
+				Exec(ctx); err != nil {
+				return err
+			}
+			if err := staging.createBareTable(ctx, tx); err != nil {
+				return err
+			}
+			for start := 0; start < len(policies); start += insertBatchSize {
+				batch := policies[start:min(start+insertBatchSize, len(policies))]
+				if _, err := staging.newInsert(ctx, tx, &batch).
+					Exec(ctx); err != nil {
+					return insertError(err)
+				}
+			}
+
+			if _, err := tx.NewDropTable().
+				Comment(a.queryComment(ctx)).
+				TableExpr("?", bun.Ident(a.tableName)).
+				Exec(ctx); err != nil {
+				return err
+			}
+			if _, err := tx.NewRaw(
+				"ALTER TABLE ? RENAME TO ?",
+				bun.Ident(staging.tableName),
+				bun.Ident(table),
+			).Comment(a.queryComment(ctx)).Exec(ctx); err != nil {
+				return err
+			}
+			return a.createIndexes(ctx, tx)
+		},
+	)
+}
+
+// upsertPolicyRecords makes the stored policy match policies without ever
+// leaving the table empty.
+func (a *Adapter) upsertPolicyRecords(ctx context.Context, policies []CasbinPolicy) error {
+	return a.conn(ctx).RunInTx(
+		ctx,
+		a.txOptions(),
+		func(ctx context.Context, tx bun.Tx) error {
+			var stored []CasbinPolicy
+			if err := a.newSelect(ctx, tx, &stored).
+				ApplyQueryBuilder(a.allowedPTypes).
+				Scan(ctx); err != nil {
+				return err
+			}
+
+			_, stale := diffPolicies(stored, policies)
+
+			// Stale rules are deleted first, as they may hold the unique
+			// columns of a new rule when WithUniqueColumns is used.
+			if err := a.deleteStaleRecords(ctx, tx, stale); err != nil {
+				return err
+			}
+
+			if len(policies) == 0 {
+				return nil
+			}
+			query, err := a.newInsertIgnore(ctx, tx, &policies)
+			if err != nil {
+				return err
+			}
+			_, err = query.Exec(ctx)
+			return err
+		},
+	)
+}
+
+// deleteStaleRecords deletes the given stored policies, by id when the table
+// has an autoincrement one and by their full key otherwise.
+func (a *Adapter) deleteStaleRecords(ctx context.Context, tx bun.Tx, stale []CasbinPolicy) error {
+	if len(stale) == 0 {
+		return nil
+	}
+
+	if a.hasSerialID() {
+		ids := make([]int64, 0, len(stale))
+		for _, policy := range stale {
+			ids = append(ids, policy.ID)
+		}
+
+		_, err := a.newDelete(ctx, tx).
+			Where("id IN (?)", bun.In(ids)).
+			Exec(ctx)
+		return err
+	}
+
+	for _, policy := range stale {
+		if _, err := a.newDelete(ctx, tx).
+			ApplyQueryBuilder(keyCondition(policy)).
+			Exec(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package casbun_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// initPGDB opens the PostgreSQL database named by CASBUN_TEST_PG_DSN, skipping
+// the test if it is not set.
+func initPGDB(t *testing.T) *bun.DB {
+	t.Helper()
+
+	dsn := os.Getenv("CASBUN_TEST_PG_DSN")
+	if dsn == "" {
+		t.Skip("CASBUN_TEST_PG_DSN is not set")
+	}
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(dsn))), pgdialect.New())
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db
+}
+
+func TestSaveSwapPostgres(t *testing.T) {
+	ctx := context.Background()
+	db := initPGDB(t)
+	table := "casbin_policies_save_swap"
+	t.Cleanup(func() {
+		_, _ = db.NewDropTable().Table(table).IfExists().Exec(ctx)
+	})
+
+	adapter, err := casbun.NewAdapter(ctx, db,
+		casbun.WithTableName(table),
+		casbun.WithSaveStrategy(casbun.SaveSwap),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.Clear(ctx); err != nil {
+		t.Fatalf("unable to clear policies: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	e.EnableAutoSave(false)
+
+	const size = 50
+	for i := 0; i < size; i++ {
+		if _, err := e.AddPolicy(fmt.Sprintf("user%d", i), "data1", "read"); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+
+	// Every save replaces one rule, so a reader must always count size rules.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for r := 0; r < cap(errs); r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				count, err := db.NewSelect().Table(table).Count(ctx)
+				if err != nil {
+					errs <- fmt.Errorf("concurrent reader failed: %w", err)
+					return
+				}
+				if count != size {
+					errs <- fmt.Errorf("concurrent reader counted %d rules, want %d", count, size)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		if _, err := e.RemovePolicy(fmt.Sprintf("user%d", i), "data1", "read"); err != nil {
+			t.Fatalf("failed to remove policy: %v", err)
+		}
+		if _, err := e.AddPolicy(fmt.Sprintf("user%d", i+size), "data1", "read"); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+		if err := e.SavePolicy(); err != nil {
+			t.Fatalf("unable to save policy: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	want, err := e.GetPolicy()
+	if err != nil {
+		t.Fatalf("unable to get policy: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	got, _ := e.GetPolicy()
+	if len(got) != len(want) {
+		t.Errorf("got %d stored rules, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,238 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+)
+
+func TestSaveUpsert(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		strategy  casbun.SaveStrategy
+		wantEmpty bool
+	}{
+		// The control case proves the reader observes the empty window left
+		// by a truncating save.
+		{name: "truncate", strategy: casbun.SaveTruncate, wantEmpty: true},
+		{name: "upsert", strategy: casbun.SaveUpsert, wantEmpty: false},
+		{name: "swap", strategy: casbun.SaveSwap, wantEmpty: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := observesEmptyPolicy(t, tt.strategy); got != tt.wantEmpty {
+				t.Errorf("concurrent reader observed an empty policy: got %v, want %v", got, tt.wantEmpty)
+			}
+		})
+	}
+}
+
+// countingHook counts the stored rows from another connection before each
+// query of a save, while it is armed, and records whether the table was ever
+// empty.
+type countingHook struct {
+	t        *testing.T
+	db       *bun.DB
+	armed    atomic.Bool
+	sawEmpty atomic.Bool
+}
+
+// hookQueryKey marks the queries of countingHook, which it does not count.
+type hookQueryKey struct{}
+
+func (h *countingHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	if !h.armed.Load() || ctx.Value(hookQueryKey{}) != nil {
+		return ctx
+	}
+
+	count, err := h.db.NewSelect().
+		Model((*casbun.CasbinPolicy)(nil)).
+		Count(context.WithValue(ctx, hookQueryKey{}, true))
+	if err != nil {
+		h.t.Errorf("concurrent reader failed: %v", err)
+		return ctx
+	}
+	if count == 0 {
+		h.sawEmpty.Store(true)
+	}
+	return ctx
+}
+
+func (h *countingHook) AfterQuery(context.Context, *bun.QueryEvent) {}
+
+// observesEmptyPolicy saves a changing policy a few times with the given
+// strategy, counting the stored rows from another connection before each
+// query of the saves, and reports whether the table was ever empty.
+func observesEmptyPolicy(t *testing.T, strategy casbun.SaveStrategy) bool {
+	t.Helper()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+	hook := &countingHook{t: t, db: db}
+	db.AddQueryHook(hook)
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithSaveStrategy(strategy))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	e.EnableAutoSave(false)
+
+	for i := 0; i < 50; i++ {
+		if _, err := e.AddPolicy(fmt.Sprintf("user%d", i), "data1", "read"); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+	}
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+
+	hook.armed.Store(true)
+	for i := 0; i < 3; i++ {
+		if _, err := e.RemovePolicy(fmt.Sprintf("user%d", i), "data1", "read"); err != nil {
+			t.Fatalf("failed to remove policy: %v", err)
+		}
+		if _, err := e.AddPolicy(fmt.Sprintf("user%d", i+50), "data1", "read"); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+		if err := e.SavePolicy(); err != nil {
+			t.Fatalf("unable to save policy: %v", err)
+		}
+	}
+	hook.armed.Store(false)
+
+	want, err := e.GetPolicy()
+	if err != nil {
+		t.Fatalf("unable to get policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, want)
+
+	return hook.sawEmpty.Load()
+}
+
+func TestSaveSwap(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithSaveStrategy(casbun.SaveSwap))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	e.EnableAutoSave(false)
+
+	for i := 0; i < 3; i++ {
+		if _, err := e.AddPolicy(fmt.Sprintf("user%d", i), "data1", "read"); err != nil {
+			t.Fatalf("failed to add policy: %v", err)
+		}
+		if err := e.SavePolicy(); err != nil {
+			t.Fatalf("unable to save policy: %v", err)
+		}
+	}
+	ensureHasPolicy(t, db, e, [][]string{{"user0", "data1", "read"}, {"user1", "data1", "read"}, {"user2", "data1", "read"}})
+
+	// The swapped table keeps the indexes of the policy table.
+	if err := adapter.ValidateSchema(ctx); err != nil {
+		t.Errorf("unexpected schema error after the swap: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"user0", "data1", "read"}); !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+}
+
+func TestRefuseEmptySave(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithRefuseEmptySave())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rule := []string{"alice", "data1", "read"}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	if err := adapter.SavePolicyCtx(ctx, model.NewModel()); !errors.Is(err, casbun.ErrEmptySave) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrEmptySave)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{rule})
+
+	if err := adapter.Clear(ctx); err != nil {
+		t.Fatalf("unable to clear policies: %v", err)
+	}
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{})
+}
+
+func TestSaveSection(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	// The model manages the grouping rules only, and holds no p rule.
+	m, _ := model.NewModelFromString(modelStr)
+	if err := m.AddPolicy("g", "g", []string{"bob", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy to model: %v", err)
+	}
+	if err := adapter.SaveSection(ctx, m, "g"); err != nil {
+		t.Fatalf("unable to save section: %v", err)
+	}
+
+	rules, err := adapter.LoadPolicyArray(ctx)
+	if err != nil {
+		t.Fatalf("failed to load policies: %v", err)
+	}
+	if want := [][]string{{"alice", "data1", "read"}, {"bob", "data2", "write"}}; !util.Array2DEquals(rules["p"], want) {
+		t.Errorf("got p rules %v, want %v", rules["p"], want)
+	}
+	if want := [][]string{{"bob", "admin"}}; !util.Array2DEquals(rules["g"], want) {
+		t.Errorf("got g rules %v, want %v", rules["g"], want)
+	}
+
+	if err := adapter.SaveSection(ctx, m); err == nil {
+		t.Error("expected an error saving no ptype")
+	}
+}
@@ -0,0 +1,362 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/feature"
+)
+
+// defaultTableName is the name of the policy table unless WithTableName is used.
+const defaultTableName = "casbin_policies"
+
+// defaultColumnLength is the length of the varchar columns storing a rule.
+const defaultColumnLength = 100
+
+// defaultColumnType is the type of the columns storing a rule, as declared by
+// CasbinPolicy.
+const defaultColumnType = "varchar(100)"
+
+// varcharPattern extracts the length of a varchar column type.
+var varcharPattern = regexp.MustCompile(`(?i)^\s*(?:n?varchar2?|character varying)\s*\(\s*(\d+)\s*\)\s*$`)
+
+// valueColumns lists the columns holding the values of a rule.
+var valueColumns = []string{"v0", "v1", "v2", "v3", "v4", "v5"}
+
+// ptypeColumn is the ptype column as an identifier, quoted by the dialect of
+// the query it is formatted in like the identifiers returned by valueColumn.
+var ptypeColumn = bun.Ident("ptype")
+
+// valueColumn returns the value column at index i as an identifier, quoted by
+// the dialect of the query it is formatted in. The conditions on the rule
+// columns reference them through ? placeholders with these identifiers, so
+// that no column name is spliced into the SQL unquoted.
+func valueColumn(i int) bun.Ident {
+	return bun.Ident(valueColumns[i])
+}
+
+// WithTableName stores the policies in the table name instead of the default
+// casbin_policies table. The name may be qualified with a schema, such as
+// "app.policies". NewAdapter fails if the name is empty.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithTableName("auth_policies"))
+func WithTableName(name string) CasbinBunOption {
+	return func(a *Adapter) {
+		if name == "" {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: table name must not be empty"))
+			return
+		}
+		a.tableName = name
+	}
+}
+
+// TableName returns the name of the table storing the policies.
+func (a *Adapter) TableName() string {
+	return a.tableName
+}
+
+// Columns returns the names of the columns storing a policy rule, the ptype
+// column followed by the value columns v0 to v5.
+func (a *Adapter) Columns() []string {
+	return append([]string{"ptype"}, valueColumns...)
+}
+
+// indexNames returns the names of the unique index and of the ptype index.
+// The default table keeps the historical index names, while custom tables get
+// names derived from the table name, as index names must be unique per schema
+// on some databases. Only the last segment of a schema-qualified table name is
+// used, as an index always lives in the schema of its table.
+func (a *Adapter) indexNames() (unique, ptype string) {
+	if a.tableName == defaultTableName {
+		return "unique_casbin_policy", "idx_casbin_ptype"
+	}
+
+	table := a.tableName
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		table = table[i+1:]
+	}
+	return "unique_" + table, "idx_" + table + "_ptype"
+}
+
+// WithColumnLength sets the length of the varchar columns storing a rule,
+// which is 100 by default. It only affects the tables created by the adapter.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithColumnLength(255))
+func WithColumnLength(n int) CasbinBunOption {
+	return func(a *Adapter) {
+		if n <= 0 {
+			a.optionErr = errors.Join(a.optionErr, fmt.Errorf("casbun: column length %d must be positive", n))
+			return
+		}
+		a.columnType = fmt.Sprintf("varchar(%d)", n)
+		a.columnLength = n
+	}
+}
+
+// WithColumnType sets the SQL type of the columns storing a rule, such as
+// "TEXT" for values of unbounded length. It only affects the tables created
+// by the adapter.
+//
+// On MySQL, columns of a type other than varchar are indexed on a prefix of
+// their values, see WithColumnLength for types with a bounded length.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithColumnType("TEXT"))
+func WithColumnType(t string) CasbinBunOption {
+	return func(a *Adapter) {
+		if strings.TrimSpace(t) == "" {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: column type must not be empty"))
+			return
+		}
+		a.columnType = t
+		a.columnLength = 0
+		if m := varcharPattern.FindStringSubmatch(t); m != nil {
+			a.columnLength, _ = strconv.Atoi(m[1])
+		}
+	}
+}
+
+// WithCollation declares the value columns v0 to v5 of the tables created by
+// the adapter with the collation name, such as NOCASE on SQLite or
+// "und-x-icu" on PostgreSQL. The collation then applies consistently to the
+// unique index, which rejects rules differing only in a way the collation
+// ignores, and to the matching of rules by every operation. The name is
+// inserted as is, so it must be quoted if the dialect requires it. NewAdapter
+// fails if name is empty or if WithCollation is combined with WithModel.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithCollation("NOCASE"))
+func WithCollation(name string) CasbinBunOption {
+	return func(a *Adapter) {
+		if strings.TrimSpace(name) == "" {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: collation must not be empty"))
+			return
+		}
+		a.collation = name
+	}
+}
+
+// valueColumnType returns the SQL type of the value columns, with the
+// collation set by WithCollation if any.
+func (a *Adapter) valueColumnType() string {
+	if a.collation == "" {
+		return a.columnType
+	}
+	return a.columnType + " COLLATE " + a.collation
+}
+
+// policyIDColumn declares the id column of the policy table, to create it
+// with value columns of a custom type.
+type policyIDColumn struct {
+	bun.BaseModel `bun:"casbin_policies"`
+	ID            int64 `bun:"id,pk,autoincrement"`
+}
+
+// policyUUIDColumn declares the id column of the policy table when it holds
+// UUIDs.
+type policyUUIDColumn struct {
+	bun.BaseModel `bun:"casbin_policies"`
+	ID            string `bun:"id,pk,type:varchar(36)"`
+}
+
+// policyGeneratedIDColumn declares the id column of the policy table when it
+// holds the ids of the generator set by WithIDGenerator.
+type policyGeneratedIDColumn struct {
+	bun.BaseModel `bun:"casbin_policies"`
+	ID            string `bun:"id,pk"`
+}
+
+// newCreateTable returns the statement creating the policy table keyed on a
+// surrogate id. Only the id column comes from a model, while the value
+// columns are declared NOT NULL with an empty string default, so that an
+// unused value is always stored as an empty string and the unique index
+// rejects a rule stored twice, as most databases do not consider NULL values
+// equal in a unique index. They stay nullable with
+// WithInsertIgnoreEmptyTrailing, which stores NULL there.
+// With WithModel, the table is derived from the model of the user.
+func (a *Adapter) newCreateTable(db bun.IDB) *bun.CreateTableQuery {
+	if a.newRow != nil {
+		return a.newModelCreateTable(db)
+	}
+
+	var idModel interface{} = (*policyIDColumn)(nil)
+	switch {
+	case a.uuidKey:
+		idModel = (*policyUUIDColumn)(nil)
+	case a.idGenerator != nil:
+		idModel = (*policyGeneratedIDColumn)(nil)
+	}
+
+	valueType := a.valueColumnType() + " NOT NULL DEFAULT ''"
+	if a.nullTrailing {
+		valueType = a.valueColumnType()
+	}
+
+	query := db.NewCreateTable().
+		Model(idModel).
+		ModelTableExpr("?", bun.Ident(a.tableName)).
+		IfNotExists().
+		ColumnExpr("? ? NOT NULL", ptypeColumn, bun.Safe(a.columnType))
+	for i := range valueColumns {
+		query = query.ColumnExpr("? ?", valueColumn(i), bun.Safe(valueType))
+	}
+	return a.withExtraColumns(query)
+}
+
+// withExtraColumns adds the columns set by WithMetadataColumn,
+// WithPriorityColumn and WithVersionColumn to query.
+func (a *Adapter) withExtraColumns(query *bun.CreateTableQuery) *bun.CreateTableQuery {
+	if a.metadataColumn != "" {
+		query = query.ColumnExpr("? TEXT", bun.Ident(a.metadataColumn))
+	}
+	if a.priorityColumn != "" {
+		query = query.ColumnExpr("? BIGINT NOT NULL DEFAULT 0", bun.Ident(a.priorityColumn))
+	}
+	if a.versionColumn != "" {
+		query = query.ColumnExpr("? BIGINT NOT NULL DEFAULT 0", bun.Ident(a.versionColumn))
+	}
+	return query
+}
+
+// WithCompositeKey makes (ptype, v0, v1, v2, v3, v4, v5) the primary key of
+// the policy table instead of a surrogate autoincrement id.
+// The table is created without the id column and without the separate unique
+// index, which the primary key makes redundant. CasbinPolicy.ID is left zero
+// on rows read back in this mode.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithCompositeKey())
+func WithCompositeKey() CasbinBunOption {
+	return func(a *Adapter) {
+		a.compositeKey = true
+	}
+}
+
+// createCompositeTableQuery returns the statement creating the policy table
+// keyed on the rule values, which takes the table name as its only argument.
+// Bun derives the default table from CasbinPolicy, but it can not express a
+// primary key that leaves the id field out.
+func (a *Adapter) createCompositeTableQuery() string {
+	fmter := a.db.Formatter()
+	columns := make([]string, 0, len(valueColumns)+2)
+	columns = append(columns, fmter.FormatQuery("? ? NOT NULL", ptypeColumn, bun.Safe(a.columnType)))
+	key := []interface{}{ptypeColumn}
+	for i := range valueColumns {
+		// Primary key columns can not hold NULL.
+		columns = append(columns, fmter.FormatQuery("? ? NOT NULL DEFAULT ''", valueColumn(i), bun.Safe(a.valueColumnType())))
+		key = append(key, valueColumn(i))
+	}
+	if a.metadataColumn != "" {
+		columns = append(columns, fmter.FormatQuery("? TEXT", bun.Ident(a.metadataColumn)))
+	}
+	if a.priorityColumn != "" {
+		columns = append(columns, fmter.FormatQuery("? BIGINT NOT NULL DEFAULT 0", bun.Ident(a.priorityColumn)))
+	}
+	if a.versionColumn != "" {
+		columns = append(columns, fmter.FormatQuery("? BIGINT NOT NULL DEFAULT 0", bun.Ident(a.versionColumn)))
+	}
+	columns = append(columns, fmter.FormatQuery("PRIMARY KEY (?)", bun.In(key)))
+
+	query := "CREATE TABLE "
+	if a.db.HasFeature(feature.TableNotExists) {
+		query += "IF NOT EXISTS "
+	}
+	return query + "? (" + strings.Join(columns, ", ") + ")"
+}
+
+// newSelect returns a query selecting policy rows into dest.
+func (a *Adapter) newSelect(ctx context.Context, db bun.IDB, dest interface{}) *bun.SelectQuery {
+	query := db.NewSelect().
+		Comment(a.queryComment(ctx)).
+		Model(dest).
+		ModelTableExpr("? AS cp", bun.Ident(a.tableName))
+	if !a.hasSerialID() {
+		query = query.ExcludeColumn("id")
+	}
+	return query
+}
+
+// hasSerialID reports whether the table is keyed on the autoincrement id held
+// by CasbinPolicy.ID.
+func (a *Adapter) hasSerialID() bool {
+	return !a.compositeKey && a.idGenerator == nil
+}
+
+// newInsert returns a query inserting the policy rows held by model, which is
+// a *CasbinPolicy or a *[]CasbinPolicy.
+func (a *Adapter) newInsert(ctx context.Context, db bun.IDB, model interface{}) *bun.InsertQuery {
+	policies := policyRecords(model)
+	switch {
+	case a.idGenerator != nil:
+		model = a.newGeneratedIDPolicies(model)
+	case a.nullTrailing:
+		model = newNullTrailingPolicies(model)
+	case a.newRow != nil:
+		model = a.newModelRows(policyRecords(model))
+	}
+	if a.priorityColumn != "" {
+		model = a.newPriorityRows(model, policies)
+	}
+
+	query := db.NewInsert().
+		Comment(a.queryComment(ctx)).
+		Model(model).
+		ModelTableExpr("?", bun.Ident(a.tableName))
+	switch {
+	case a.compositeKey:
+		query = query.ExcludeColumn("id").Returning("NULL")
+	case a.idGenerator != nil:
+		query = query.Returning("NULL")
+	}
+	return query
+}
+
+// policyRecords returns the policies held by model, a *CasbinPolicy or a
+// *[]CasbinPolicy.
+func policyRecords(model interface{}) []CasbinPolicy {
+	switch model := model.(type) {
+	case *CasbinPolicy:
+		return []CasbinPolicy{*model}
+	case *[]CasbinPolicy:
+		return *model
+	}
+	return nil
+}
+
+// newUpdate returns a query updating policy rows from model.
+// The table is not aliased, as not every dialect accepts an alias in UPDATE.
+func (a *Adapter) newUpdate(ctx context.Context, db bun.IDB, model interface{}) *bun.UpdateQuery {
+	return db.NewUpdate().
+		Comment(a.queryComment(ctx)).
+		Model(model).
+		ModelTableExpr("?", bun.Ident(a.tableName))
+}
+
+// newDelete returns a query deleting policy rows.
+// The table is not aliased, as not every dialect accepts an alias in DELETE.
+func (a *Adapter) newDelete(ctx context.Context, db bun.IDB) *bun.DeleteQuery {
+	return db.NewDelete().
+		Comment(a.queryComment(ctx)).
+		Model((*CasbinPolicy)(nil)).
+		ModelTableExpr("?", bun.Ident(a.tableName))
+}
+
+// newTruncate returns a query removing every policy row.
+func (a *Adapter) newTruncate(ctx context.Context, db bun.IDB) *bun.TruncateTableQuery {
+	return db.NewTruncateTable().
+		Comment(a.queryComment(ctx)).
+		Model((*CasbinPolicy)(nil)).
+		ModelTableExpr("?", bun.Ident(a.tableName))
+}
@@ -0,0 +1,531 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/google/uuid"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+)
+
+func TestCompositeKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithCompositeKey())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	var idColumns int
+	if err := db.NewRaw(
+		"SELECT count(*) FROM pragma_table_info('casbin_policies') WHERE name = 'id'",
+	).Scan(ctx, &idColumns); err != nil {
+		t.Fatalf("unable to inspect table: %v", err)
+	}
+	if idColumns != 0 {
+		t.Errorf("table should not have an id column")
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+		{"alice", "data2", "write"},
+	}
+	if _, err := e.AddPolicies(policies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	ensureHasPolicy(t, db, e, policies)
+
+	if err := adapter.AddPolicy("p", "p", policies[0]); !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got %v, want %v", err, casbun.ErrPolicyExists)
+	}
+
+	if ok, err := e.UpdatePolicy(policies[0], []string{"alice", "data1", "read"}); !ok || err != nil {
+		t.Fatalf("unable to update policy: %v", err)
+	}
+
+	if ok, err := e.RemovePolicy("bob", "data1", "read"); !ok || err != nil {
+		t.Fatalf("unable to remove policy: %v", err)
+	}
+
+	want := [][]string{{"alice", "data1", "read"}, {"alice", "data2", "write"}}
+	ensureHasPolicy(t, db, e, want)
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, want)
+
+	if err := e.SavePolicy(); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, want)
+}
+
+func TestTableName(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithTableName("auth_policies"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if got := adapter.TableName(); got != "auth_policies" {
+		t.Errorf("got table name %q, want %q", got, "auth_policies")
+	}
+
+	wantColumns := []string{"ptype", "v0", "v1", "v2", "v3", "v4", "v5"}
+	if got := adapter.Columns(); !slices.Equal(got, wantColumns) {
+		t.Errorf("got columns %v, want %v", got, wantColumns)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	policies := [][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+	}
+	if _, err := e.AddPolicies(policies); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	if ok, err := e.RemovePolicy("bob", "data1", "read"); !ok || err != nil {
+		t.Fatalf("unable to remove policy: %v", err)
+	}
+
+	var count int
+	if err := db.NewRaw("SELECT count(*) FROM auth_policies").Scan(ctx, &count); err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("got %d rows in auth_policies, want 1", count)
+	}
+
+	var tables int
+	if err := db.NewRaw(
+		"SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'casbin_policies'",
+	).Scan(ctx, &tables); err != nil {
+		t.Fatalf("unable to inspect schema: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("default table should not be created")
+	}
+}
+
+func TestDefaultTableName(t *testing.T) {
+	t.Parallel()
+
+	adapter, err := casbun.NewAdapter(context.Background(), initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if got := adapter.TableName(); got != "casbin_policies" {
+		t.Errorf("got table name %q, want %q", got, "casbin_policies")
+	}
+}
+
+func TestEmptyTableName(t *testing.T) {
+	t.Parallel()
+
+	if _, err := casbun.NewAdapter(context.Background(), initDB(), casbun.WithTableName("")); err == nil {
+		t.Errorf("expected an error for an empty table name")
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	t.Parallel()
+
+	subject := "urn:example:" + strings.Repeat("x", 288)
+
+	tests := []struct {
+		name     string
+		opt      casbun.CasbinBunOption
+		wantType string
+	}{
+		{name: "length", opt: casbun.WithColumnLength(500), wantType: "varchar(500)"},
+		{name: "text", opt: casbun.WithColumnType("TEXT"), wantType: "TEXT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			db := initDB()
+			adapter, err := casbun.NewAdapter(ctx, db, tt.opt)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			var columnType string
+			if err := db.NewRaw(
+				"SELECT type FROM pragma_table_info('casbin_policies') WHERE name = 'v0'",
+			).Scan(ctx, &columnType); err != nil {
+				t.Fatalf("unable to inspect table: %v", err)
+			}
+			if columnType != tt.wantType {
+				t.Errorf("got column type %q, want %q", columnType, tt.wantType)
+			}
+
+			if err := adapter.AddPolicy("p", "p", []string{subject, "data1", "read"}); err != nil {
+				t.Fatalf("failed to add policy: %v", err)
+			}
+
+			m, _ := model.NewModelFromString(modelStr)
+			if err := adapter.LoadPolicy(m); err != nil {
+				t.Fatalf("unable to load policy: %v", err)
+			}
+			got, _ := m.GetPolicy("p", "p")
+			if len(got) != 1 || got[0][0] != subject {
+				t.Errorf("got %v, want the %d characters subject", got, len(subject))
+			}
+		})
+	}
+
+	if _, err := casbun.NewAdapter(context.Background(), initDB(), casbun.WithColumnLength(0)); err == nil {
+		t.Errorf("expected an error for a zero column length")
+	}
+}
+
+func TestPtypeIndex(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    int
+	}{
+		{name: "enabled", enabled: true, want: 1},
+		{name: "disabled", enabled: false, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			db := initDB()
+			// The adapter is kept alive until the end, as its finalizer
+			// closes db.
+			adapter, err := casbun.NewAdapter(ctx, db, casbun.WithPtypeIndex(tt.enabled))
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			var indexes, unique int
+			if err := db.NewRaw(
+				"SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_casbin_ptype'",
+			).Scan(ctx, &indexes); err != nil {
+				t.Fatalf("unable to inspect schema: %v", err)
+			}
+			if indexes != tt.want {
+				t.Errorf("got %d ptype indexes, want %d", indexes, tt.want)
+			}
+
+			if err := db.NewRaw(
+				"SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = 'unique_casbin_policy'",
+			).Scan(ctx, &unique); err != nil {
+				t.Fatalf("unable to inspect schema: %v", err)
+			}
+			if unique != 1 {
+				t.Errorf("the unique index should be created regardless of the ptype index")
+			}
+			runtime.KeepAlive(adapter)
+		})
+	}
+}
+
+func TestUUIDKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithUUIDKey())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	e, err := casbin.NewEnforcer(m, adapter)
+	if err != nil {
+		t.Fatalf("failed to create enforcer: %v", err)
+	}
+
+	if _, err := e.AddPolicies([][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if _, err := e.AddPolicy("carol", "data2", "read"); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	var ids []string
+	if err := db.NewRaw("SELECT id FROM casbin_policies").Scan(ctx, &ids); err != nil {
+		t.Fatalf("unable to read ids: %v", err)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("got %d ids, want 3", len(ids))
+	}
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			t.Errorf("id %q is not a valid UUID: %v", id, err)
+		}
+	}
+
+	if ok, err := e.UpdatePolicy([]string{"alice", "data1", "write"}, []string{"alice", "data1", "read"}); !ok || err != nil {
+		t.Fatalf("unable to update policy: %v", err)
+	}
+	if ok, err := e.RemovePolicy("bob", "data1", "read"); !ok || err != nil {
+		t.Fatalf("unable to remove policy: %v", err)
+	}
+
+	if err := e.LoadPolicy(); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	ensureHasPolicy(t, db, e, [][]string{
+		{"alice", "data1", "read"},
+		{"carol", "data2", "read"},
+	})
+
+	if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithUUIDKey(), casbun.WithCompositeKey()); err == nil {
+		t.Errorf("expected an error when combining UUID and composite keys")
+	}
+}
+
+func TestUniqueColumns(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithUniqueColumns("ptype", "v0", "v1", "v2"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read", "allow"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	// Rules differing only outside the unique columns are duplicates.
+	err = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read", "deny"})
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "write", "deny"}); err != nil {
+		t.Errorf("failed to add policy differing in a unique column: %v", err)
+	}
+
+	added, err := adapter.AddPoliciesIgnoreExisting(ctx, "p", [][]string{
+		{"alice", "data1", "read", "deny"},
+		{"bob", "data1", "read", "allow"},
+	})
+	if err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if added != 1 {
+		t.Errorf("got %d added rules, want 1", added)
+	}
+
+	if err := adapter.ValidateSchema(ctx); err != nil {
+		t.Errorf("unexpected schema error: %v", err)
+	}
+
+	for name, opts := range map[string][]casbun.CasbinBunOption{
+		"unknown column":  {casbun.WithUniqueColumns("ptype", "v6")},
+		"repeated column": {casbun.WithUniqueColumns("ptype", "v0", "v0")},
+		"no column":       {casbun.WithUniqueColumns()},
+		"composite key":   {casbun.WithUniqueColumns("ptype", "v0"), casbun.WithCompositeKey()},
+	} {
+		if _, err := casbun.NewAdapter(ctx, initDB(), opts...); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestUniqueColumnsSaveUpsert(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(
+		ctx,
+		initDB(),
+		casbun.WithUniqueColumns("ptype", "v0", "v1"),
+		casbun.WithSaveStrategy(casbun.SaveUpsert),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	// The saved rule replaces the stored one holding the same unique columns.
+	m, _ := model.NewModelFromString(modelStr)
+	if err := m.AddPolicy("p", "p", []string{"alice", "data1", "write"}); err != nil {
+		t.Fatalf("unable to add policy to the model: %v", err)
+	}
+	if err := adapter.SavePolicyCtx(ctx, m); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+
+	got, err := adapter.GetFilteredPolicy(ctx, "p", 0)
+	if err != nil {
+		t.Fatalf("unable to get policies: %v", err)
+	}
+	if want := [][]string{{"p", "alice", "data1", "write"}}; len(got) != 1 || !slices.Equal(got[0], want[0]) {
+		t.Errorf("got policies %v, want %v", got, want)
+	}
+}
+
+func TestUniqueIndex(t *testing.T) {
+	t.Parallel()
+
+	countUnique := func(t *testing.T, db *bun.DB) int {
+		t.Helper()
+
+		var unique int
+		if err := db.NewRaw(
+			"SELECT count(*) FROM sqlite_master WHERE type = 'index' AND name = 'unique_casbin_policy'",
+		).Scan(context.Background(), &unique); err != nil {
+			t.Fatalf("unable to inspect schema: %v", err)
+		}
+		return unique
+	}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    int
+	}{
+		{name: "enabled", enabled: true, want: 1},
+		{name: "disabled", enabled: false, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			db := initFileDB(t)
+			adapter, err := casbun.NewAdapter(ctx, db, casbun.WithUniqueIndex(tt.enabled))
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+			if got := countUnique(t, db); got != tt.want {
+				t.Errorf("got %d unique indexes, want %d", got, tt.want)
+			}
+
+			rule := []string{"alice", "data1", "read"}
+			if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+				t.Fatalf("failed to add policy: %v", err)
+			}
+			err = adapter.AddPolicyCtx(ctx, "p", "p", rule)
+			if tt.enabled != errors.Is(err, casbun.ErrPolicyExists) {
+				t.Errorf("got error %v adding a duplicate rule", err)
+			}
+
+			// The index can not be added while the rules hold duplicates.
+			if err := adapter.EnsureUniqueIndex(ctx); tt.enabled != (err == nil) {
+				t.Errorf("got error %v ensuring the unique index", err)
+			}
+			if _, err := adapter.RemoveAllByPType(ctx, "p"); err != nil {
+				t.Fatalf("unable to remove policies: %v", err)
+			}
+			if err := adapter.EnsureUniqueIndex(ctx); err != nil {
+				t.Fatalf("unable to ensure the unique index: %v", err)
+			}
+			if got := countUnique(t, db); got != 1 {
+				t.Errorf("got %d unique indexes after ensuring it, want 1", got)
+			}
+		})
+	}
+
+	_, err := casbun.NewAdapter(context.Background(), initDB(), casbun.WithUniqueIndex(false), casbun.WithCompositeKey())
+	if err == nil {
+		t.Errorf("expected an error disabling the unique index with a composite key")
+	}
+}
+
+func TestNullSafeUniqueIndex(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+
+	// A rule with an empty trailing value is the same logical rule.
+	err = adapter.AddPolicyCtx(ctx, "g", "g", []string{"alice", "admin", ""})
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+
+	// Rows written by other tools get empty strings in the columns they omit,
+	// and can not hold NULL there.
+	if _, err := db.ExecContext(ctx, "INSERT INTO casbin_policies (ptype, v0, v1) VALUES ('g', 'alice', 'admin')"); err == nil {
+		t.Error("expected the unique index to reject a row omitting the unused columns")
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO casbin_policies (ptype, v0, v1, v2) VALUES ('g', 'bob', 'admin', NULL)"); err == nil {
+		t.Error("expected a NULL value to be rejected")
+	}
+}
+
+func TestCollation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithCollation("NOCASE"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"Alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	err = adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+	if !errors.Is(err, casbun.ErrPolicyExists) {
+		t.Errorf("got error %v, want %v", err, casbun.ErrPolicyExists)
+	}
+
+	// Rules are matched with the collation too.
+	if err := adapter.RemovePolicyCtx(ctx, "p", "p", []string{"ALICE", "data1", "read"}); err != nil {
+		t.Fatalf("failed to remove policy: %v", err)
+	}
+	count, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("got %d rules after the removal, want 0", count)
+	}
+
+	if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithCollation(" ")); err == nil {
+		t.Errorf("expected an error for an empty collation")
+	}
+}
@@ -0,0 +1,48 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+
+	"github.com/uptrace/bun"
+)
+
+// SearchPolicies returns the stored rules holding needle in any of their
+// values, whatever its position, such as every rule mentioning data1 for a
+// search UI. Values are compared for equality, ignoring the case with
+// WithCaseInsensitive. Each rule is returned with its ptype first, such as
+// ["p", "alice", "data1", "read"]. It fails if needle is empty.
+//
+// Example:
+//
+//	rules, err := adapter.SearchPolicies(ctx, "data1")
+func (a *Adapter) SearchPolicies(ctx context.Context, needle string) (_ [][]string, err error) {
+	defer wrapOpError("search_policies", "", &err)
+	defer a.observe("search_policies")()
+
+	if needle == "" {
+		return nil, errors.New("casbun: search needle must not be empty")
+	}
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	out := make([][]string, 0)
+	for _, r := range a.routes() {
+		policies, err := r.selectLoadedPolicies(ctx, func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				for i := range valueColumns {
+					q = q.WhereOr(equalCondition(a.caseInsensitive), valueColumn(i), needle)
+				}
+				return q
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range policies {
+			out = append(out, policy.toSlice())
+		}
+	}
+	return out, nil
+}
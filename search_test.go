@@ -0,0 +1,75 @@
+package casbun_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestSearchPolicies(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data2", "write"},
+		{"data1_admin", "data2", "read"},
+		{"carol", "data3", "read", "data1"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPoliciesCtx(ctx, "g", "g2", [][]string{
+		{"data1", "datasets"},
+		{"data11", "datasets"},
+	}); err != nil {
+		t.Fatalf("failed to add grouping policies: %v", err)
+	}
+
+	got, err := adapter.SearchPolicies(ctx, "data1")
+	if err != nil {
+		t.Fatalf("unable to search policies: %v", err)
+	}
+	want := [][]string{
+		{"p", "alice", "data1", "read"},
+		{"p", "carol", "data3", "read", "data1"},
+		{"g2", "data1", "datasets"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got rules %v, want %v", got, want)
+	}
+
+	if got, err := adapter.SearchPolicies(ctx, "data9"); err != nil || len(got) != 0 {
+		t.Errorf("got rules %v and error %v for an unknown value, want none", got, err)
+	}
+	if _, err := adapter.SearchPolicies(ctx, ""); err == nil {
+		t.Errorf("expected an error for an empty needle")
+	}
+}
+
+func TestSearchPoliciesCaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithCaseInsensitive())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "Data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	got, err := adapter.SearchPolicies(ctx, "data1")
+	if err != nil {
+		t.Fatalf("unable to search policies: %v", err)
+	}
+	if want := [][]string{{"p", "alice", "Data1", "read"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got rules %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package casbun
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// insertBatchSize bounds the number of rows inserted by a single statement
+// when adding rules or restoring a snapshot, keeping it below the bind
+// parameter limits of every dialect.
+const insertBatchSize = 250
+
+// Snapshot returns every stored policy, for backups. The policies are ordered
+// by id, or by rule when WithCompositeKey is used. With WithPTypeTable, the
+// policies of each table follow each other, those of the table set by
+// WithTableName first.
+func (a *Adapter) Snapshot(ctx context.Context) (_ []CasbinPolicy, err error) {
+	defer wrapOpError("snapshot", "", &err)
+	defer a.observe("snapshot")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	order := []string{"id"}
+	if a.compositeKey {
+		order = append([]string{"ptype"}, valueColumns...)
+	}
+
+	policies := make([]CasbinPolicy, 0)
+	for _, r := range a.routes() {
+		var routed []CasbinPolicy
+		if err := r.newSelect(ctx, r.reader(ctx), &routed).
+			ApplyQueryBuilder(r.allowedPTypes).
+			Order(order...).
+			Scan(ctx); err != nil {
+			return nil, err
+		}
+		policies = append(policies, routed...)
+	}
+	return policies, nil
+}
+
+// WithPreserveIDs keeps the ids of the stored rules, so that external
+// references to them survive: Restore inserts the policies with their ids,
+// and SavePolicy keeps the ids of the rules that were already stored while
+// the new rules get new ones. It requires the default autoincrement id, and
+// NewAdapter fails if it is combined with WithCompositeKey or WithUUIDKey.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithPreserveIDs())
+func WithPreserveIDs() CasbinBunOption {
+	return func(a *Adapter) {
+		a.preserveIDs = true
+	}
+}
+
+// Restore replaces every stored policy with policies, typically returned by
+// Snapshot, in a single transaction. Readers observe either the old or the
+// restored policy. With WithPTypeTable, each policy is restored in the table
+// of its ptype. The ids of policies are not kept, the database assigns new
+// ones, unless WithPreserveIDs is used.
+func (a *Adapter) Restore(ctx context.Context, policies []CasbinPolicy) (err error) {
+	defer wrapOpError("restore", "", &err)
+	defer a.observe("restore")()
+
+	if err := a.checkAllowedPolicies(policies); err != nil {
+		return err
+	}
+
+	rows := make([]CasbinPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if !a.preserveIDs {
+			policy.ID = 0
+		}
+		rows = append(rows, policy)
+	}
+
+	err = a.retry(ctx, func(ctx context.Context) error {
+		return a.conn(ctx).RunInTx(
+			ctx,
+			a.txOptions(),
+			func(ctx context.Context, tx bun.Tx) error {
+				routes, grouped := a.routePolicies(rows)
+				for i, r := range routes {
+					// Unlike TRUNCATE, DELETE is transactional on every dialect.
+					if _, err := r.newDelete(ctx, tx).
+						Where("1 = 1").
+						ApplyQueryBuilder(r.allowedPTypes).
+						Exec(ctx); err != nil {
+						return err
+					}
+
+					rows := grouped[i]
+					for start := 0; start < len(rows); start += insertBatchSize {
+						batch := rows[start:min(start+insertBatchSize, len(rows))]
+						if _, err := r.newInsert(ctx, tx, &batch).
+							Exec(ctx); err != nil {
+							return insertError(err)
+						}
+					}
+					if r.preserveIDs {
+						if err := r.resetIDSequence(ctx, tx); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			},
+		)
+	})
+	return a.notify(ctx, err, PolicyEvent{Op: EventSave})
+}
+
+// resetIDSequence moves the sequence generating the ids past the largest
+// stored id, after rows were inserted with explicit ids. Only PostgreSQL
+// needs it, as the other dialects derive the next id from the stored ones.
+func (a *Adapter) resetIDSequence(ctx context.Context, db bun.IDB) error {
+	if !dialectOf(db.Dialect().Name()).serialSequence {
+		return nil
+	}
+
+	_, err := db.NewRaw(
+		"SELECT setval(pg_get_serial_sequence(?, 'id'), COALESCE((SELECT MAX(id) FROM ?), 0) + 1, false)",
+		a.tableName,
+		bun.Ident(a.tableName),
+	).Comment(a.queryComment(ctx)).Exec(ctx)
+	return err
+}
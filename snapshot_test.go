@@ -0,0 +1,133 @@
+package casbun_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	// More rules than a single insert batch holds.
+	rules := make([][]string, 0, 600)
+	for i := 0; i < 600; i++ {
+		rules = append(rules, []string{fmt.Sprintf("user%d", i), "data1", "read"})
+	}
+	if err := adapter.AddPolicies("p", "p", rules); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicy("g", "g", []string{"user1", "admin"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	snapshot, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if len(snapshot) != 601 {
+		t.Fatalf("got %d policies in the snapshot, want 601", len(snapshot))
+	}
+
+	if err := adapter.Clear(ctx); err != nil {
+		t.Fatalf("unable to clear policies: %v", err)
+	}
+
+	if err := adapter.Restore(ctx, snapshot); err != nil {
+		t.Fatalf("unable to restore: %v", err)
+	}
+
+	restored, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(withoutIDs(restored), withoutIDs(snapshot)) {
+		t.Errorf("restored policies differ from the snapshot")
+	}
+}
+
+func withoutIDs(policies []casbun.CasbinPolicy) []casbun.CasbinPolicy {
+	out := make([]casbun.CasbinPolicy, 0, len(policies))
+	for _, policy := range policies {
+		policy.ID = 0
+		out = append(out, policy)
+	}
+	return out
+}
+
+func TestPreserveIDs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithPreserveIDs())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "read"},
+		{"carol", "data1", "read"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	// Leave a gap in the ids.
+	if err := adapter.RemovePolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to remove policy: %v", err)
+	}
+
+	snapshot, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if err := adapter.Clear(ctx); err != nil {
+		t.Fatalf("unable to clear policies: %v", err)
+	}
+	if err := adapter.Restore(ctx, snapshot); err != nil {
+		t.Fatalf("unable to restore: %v", err)
+	}
+
+	restored, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if !reflect.DeepEqual(restored, snapshot) {
+		t.Errorf("got restored policies %v, want %v", restored, snapshot)
+	}
+
+	m, _ := model.NewModelFromString(modelStr)
+	if err := adapter.LoadPolicy(m); err != nil {
+		t.Fatalf("unable to load policy: %v", err)
+	}
+	if err := m.AddPolicy("p", "p", []string{"dave", "data1", "read"}); err != nil {
+		t.Fatalf("unable to add policy to the model: %v", err)
+	}
+	if err := adapter.SavePolicy(m); err != nil {
+		t.Fatalf("unable to save policy: %v", err)
+	}
+
+	saved, err := adapter.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("unable to snapshot: %v", err)
+	}
+	if len(saved) != 3 || !reflect.DeepEqual(saved[:2], snapshot) {
+		t.Fatalf("got saved policies %v, want %v followed by the new rule", saved, snapshot)
+	}
+	if saved[2].ID <= snapshot[1].ID {
+		t.Errorf("got id %d for the new rule, want more than %d", saved[2].ID, snapshot[1].ID)
+	}
+
+	if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithPreserveIDs(), casbun.WithCompositeKey()); err == nil {
+		t.Errorf("expected an error preserving ids with a composite key")
+	}
+}
@@ -0,0 +1,82 @@
+package casbun
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Stats reports the health and volume of the policy storage.
+type Stats struct {
+	// DB holds the connection pool statistics of the primary database.
+	DB sql.DBStats
+	// PolicyCounts holds the number of stored rules of each ptype.
+	PolicyCounts map[string]int
+}
+
+// Stats returns the connection pool statistics of the primary database and
+// the number of stored rules of each ptype, counted by a single grouped query.
+func (a *Adapter) Stats(ctx context.Context) (_ Stats, err error) {
+	defer wrapOpError("stats", "", &err)
+	defer a.observe("stats")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return Stats{}, err
+	}
+
+	var counts []struct {
+		PType string `bun:"ptype"`
+		Count int    `bun:"count"`
+	}
+	if err := a.newSelect(ctx, a.reader(ctx), (*CasbinPolicy)(nil)).
+		ExcludeColumn("*").
+		Column("ptype").
+		ColumnExpr("count(*) AS count").
+		Group("ptype").
+		Scan(ctx, &counts); err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{
+		DB:           a.db.DB.Stats(),
+		PolicyCounts: make(map[string]int, len(counts)),
+	}
+	for _, c := range counts {
+		stats.PolicyCounts[c.PType] = c.Count
+	}
+	return stats, nil
+}
+
+// ListPTypes returns the distinct ptypes of the stored rules in ascending
+// order, so that tooling inspecting an unknown policy database can decide
+// which sections to load.
+//
+// Example:
+//
+//	ptypes, err := adapter.ListPTypes(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, ptype := range ptypes {
+//	    filter := casbun.Filter{PType: []string{ptype}}
+//	    if err := adapter.LoadIncrementalFilteredPolicyCtx(ctx, model, filter); err != nil {
+//	        return err
+//	    }
+//	}
+func (a *Adapter) ListPTypes(ctx context.Context) (_ []string, err error) {
+	defer wrapOpError("list_ptypes", "", &err)
+	defer a.observe("list_ptypes")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return nil, err
+	}
+
+	ptypes := make([]string, 0)
+	if err := a.newSelect(ctx, a.reader(ctx), (*CasbinPolicy)(nil)).
+		ExcludeColumn("*").
+		ColumnExpr("DISTINCT ?", ptypeColumn).
+		Order("ptype").
+		Scan(ctx, &ptypes); err != nil {
+		return nil, err
+	}
+	return ptypes, nil
+}
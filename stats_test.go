@@ -0,0 +1,80 @@
+package casbun_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := adapter.AddPolicies("p", "p", [][]string{
+		{"alice", "data1", "read"},
+		{"bob", "data1", "write"},
+	}); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+	if err := adapter.AddPolicy("g", "g", []string{"alice", "admin"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	stats, err := adapter.Stats(ctx)
+	if err != nil {
+		t.Fatalf("unable to get stats: %v", err)
+	}
+
+	want := map[string]int{"p": 2, "g": 1}
+	if !maps.Equal(stats.PolicyCounts, want) {
+		t.Errorf("got policy counts %v, want %v", stats.PolicyCounts, want)
+	}
+
+	if stats.DB.OpenConnections == 0 {
+		t.Errorf("pool stats should report an open connection")
+	}
+}
+
+func TestListPTypes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	ptypes, err := adapter.ListPTypes(ctx)
+	if err != nil {
+		t.Fatalf("unable to list ptypes: %v", err)
+	}
+	if len(ptypes) != 0 {
+		t.Errorf("got ptypes %v from an empty table, want none", ptypes)
+	}
+
+	for ptype, rules := range map[string][][]string{
+		"p":  {{"alice", "data1", "read"}, {"bob", "data2", "write"}},
+		"g":  {{"alice", "admin"}},
+		"g2": {{"data1", "group1"}, {"data2", "group1"}},
+	} {
+		if err := adapter.AddPoliciesCtx(ctx, ptype[:1], ptype, rules); err != nil {
+			t.Fatalf("failed to add policies: %v", err)
+		}
+	}
+
+	ptypes, err = adapter.ListPTypes(ctx)
+	if err != nil {
+		t.Fatalf("unable to list ptypes: %v", err)
+	}
+	if want := []string{"g", "g2", "p"}; !slices.Equal(ptypes, want) {
+		t.Errorf("got ptypes %v, want %v", ptypes, want)
+	}
+}
@@ -0,0 +1,67 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// timedOperations lists the operations accepted by WithOperationTimeouts.
+var timedOperations = map[string]bool{
+	"load_policy":                      true,
+	"load_filtered_policy":             true,
+	"load_incremental_filtered_policy": true,
+	"save_policy":                      true,
+	"add_policy":                       true,
+	"add_policies":                     true,
+	"remove_policy":                    true,
+	"remove_policies":                  true,
+	"remove_filtered_policy":           true,
+	"update_policy":                    true,
+	"update_policies":                  true,
+	"update_filtered_policies":         true,
+}
+
+// WithOperationTimeouts bounds the duration of the methods without a context
+// parameter, which Casbin calls through its legacy interfaces. The timeouts
+// are keyed by operation, named after the method in snake case like the
+// operations reported to Metrics:
+//
+//	load_policy, load_filtered_policy, load_incremental_filtered_policy,
+//	save_policy, add_policy, add_policies, remove_policy, remove_policies,
+//	remove_filtered_policy, update_policy, update_policies and
+//	update_filtered_policies.
+//
+// Operations missing from timeouts are not bounded. The timeouts apply on top
+// of the context set by WithContext. The methods taking a context are bounded
+// by their context only. NewAdapter fails on an unknown operation or a
+// timeout that is not positive.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithOperationTimeouts(map[string]time.Duration{
+//	    "save_policy": time.Minute,
+//	    "add_policy":  time.Second,
+//	}))
+func WithOperationTimeouts(timeouts map[string]time.Duration) CasbinBunOption {
+	return func(a *Adapter) {
+		for name, timeout := range timeouts {
+			if !timedOperations[name] {
+				a.optionErr = errors.Join(a.optionErr, fmt.Errorf("casbun: unknown operation %q", name))
+			} else if timeout <= 0 {
+				a.optionErr = errors.Join(a.optionErr, fmt.Errorf("casbun: timeout of %s must be positive", name))
+			}
+		}
+		a.timeouts = timeouts
+	}
+}
+
+// operationContext returns the context of the operation name called without
+// a context, bounded by its timeout if any.
+func (a *Adapter) operationContext(name string) (context.Context, context.CancelFunc) {
+	if timeout, ok := a.timeouts[name]; ok {
+		return context.WithTimeout(a.ctx, timeout)
+	}
+	return a.ctx, func() {}
+}
@@ -0,0 +1,55 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestOperationTimeouts(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	db.SetMaxOpenConns(1)
+	adapter, err := casbun.NewAdapter(ctx, db, casbun.WithOperationTimeouts(map[string]time.Duration{
+		"add_policy": 50 * time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	// Holding the only connection blocks every statement of the adapter.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("unable to get connection: %v", err)
+	}
+
+	start := time.Now()
+	err = adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("add policy took %v, want about 50ms", elapsed)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("unable to release connection: %v", err)
+	}
+	if err := adapter.AddPolicy("p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Errorf("failed to add policy: %v", err)
+	}
+
+	for name, timeouts := range map[string]map[string]time.Duration{
+		"unknown operation": {"add_policy_ctx": time.Second},
+		"negative timeout":  {"save_policy": -time.Second},
+	} {
+		if _, err := casbun.NewAdapter(ctx, initDB(), casbun.WithOperationTimeouts(timeouts)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
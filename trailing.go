@@ -0,0 +1,75 @@
+package casbun
+
+import (
+	"database/sql"
+
+	"github.com/uptrace/bun"
+)
+
+// WithInsertIgnoreEmptyTrailing stores the value columns left unused by a
+// rule as NULL instead of empty strings, for tables shared with tools that
+// expect NULL there. Empty values followed by a non-empty one are still
+// stored as empty strings. Loads and the operations matching rules treat NULL
+// and empty values alike, whichever way the rows were stored.
+//
+// The value columns of the tables created by the adapter are then nullable,
+// while they are otherwise NOT NULL with an empty string default. Most
+// databases do not consider NULL values equal in a unique index, so the unique
+// index does not reject a rule stored twice with NULL values.
+// It requires the default autoincrement id, and NewAdapter fails if it is
+// combined with WithCompositeKey or WithUUIDKey.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithInsertIgnoreEmptyTrailing())
+func WithInsertIgnoreEmptyTrailing() CasbinBunOption {
+	return func(a *Adapter) {
+		a.nullTrailing = true
+	}
+}
+
+// nullTrailingCasbinPolicy is the row inserted by an adapter using
+// WithInsertIgnoreEmptyTrailing.
+type nullTrailingCasbinPolicy struct {
+	bun.BaseModel `bun:"casbin_policies,alias:cp"`
+	ID            int64          `bun:"id,pk,autoincrement"`
+	PType         string         `bun:"ptype"`
+	V0            sql.NullString `bun:"v0"`
+	V1            sql.NullString `bun:"v1"`
+	V2            sql.NullString `bun:"v2"`
+	V3            sql.NullString `bun:"v3"`
+	V4            sql.NullString `bun:"v4"`
+	V5            sql.NullString `bun:"v5"`
+}
+
+// newNullTrailingPolicies returns the rows inserting the policies held by
+// model, a *CasbinPolicy or a *[]CasbinPolicy, with NULL in the value columns
+// following the last non-empty value.
+func newNullTrailingPolicies(model interface{}) *[]nullTrailingCasbinPolicy {
+	policies := policyRecords(model)
+	rows := make([]nullTrailingCasbinPolicy, 0, len(policies))
+	for _, p := range policies {
+		values := p.values()
+		used := len(values)
+		for used > 0 && values[used-1] == "" {
+			used--
+		}
+
+		nullable := make([]sql.NullString, len(values))
+		for i := 0; i < used; i++ {
+			nullable[i] = sql.NullString{String: values[i], Valid: true}
+		}
+
+		rows = append(rows, nullTrailingCasbinPolicy{
+			ID:    p.ID,
+			PType: p.PType,
+			V0:    nullable[0],
+			V1:    nullable[1],
+			V2:    nullable[2],
+			V3:    nullable[3],
+			V4:    nullable[4],
+			V5:    nullable[5],
+		})
+	}
+	return &rows
+}
@@ -0,0 +1,106 @@
+package casbun_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestInsertIgnoreEmptyTrailing(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+	nulls, err := casbun.NewAdapter(ctx, db, casbun.WithInsertIgnoreEmptyTrailing())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	empties, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if err := nulls.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	if err := nulls.AddPolicyCtx(ctx, "g", "g", []string{"bob", "admin"}); err != nil {
+		t.Fatalf("failed to add grouping policy: %v", err)
+	}
+	if err := empties.AddPolicyCtx(ctx, "p", "p", []string{"carol", "data2", "read"}); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	// Only the unused trailing columns hold NULL.
+	nullCount, err := db.NewSelect().
+		Model((*casbun.CasbinPolicy)(nil)).
+		Where("v3 IS NULL").
+		Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if nullCount != 2 {
+		t.Errorf("got %d rules with NULL trailing values, want 2", nullCount)
+	}
+	emptyCount, err := db.NewSelect().
+		Model((*casbun.CasbinPolicy)(nil)).
+		Where("v3 = ''").
+		Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if emptyCount != 1 {
+		t.Errorf("got %d rules with empty trailing values, want 1", emptyCount)
+	}
+
+	wantPolicy := [][]string{
+		{"alice", "data1", "read"},
+		{"carol", "data2", "read"},
+	}
+	wantGrouping := [][]string{{"bob", "admin"}}
+	for _, adapter := range []*casbun.Adapter{nulls, empties} {
+		m, _ := model.NewModelFromString(modelStr)
+		if err := adapter.LoadPolicyCtx(ctx, m); err != nil {
+			t.Fatalf("unable to load policy: %v", err)
+		}
+		if got := m["p"]["p"].Policy; !util.SortedArray2DEquals(wantPolicy, got) {
+			t.Errorf("got policy %v, want %v", got, wantPolicy)
+		}
+		if got := m["g"]["g"].Policy; !util.Array2DEquals(wantGrouping, got) {
+			t.Errorf("got grouping policy %v, want %v", got, wantGrouping)
+		}
+	}
+
+	existing, err := empties.ExistingPolicies(ctx, "g", wantGrouping)
+	if err != nil {
+		t.Fatalf("unable to check existing policies: %v", err)
+	}
+	if !util.Array2DEquals(wantGrouping, existing) {
+		t.Errorf("got existing policies %v, want %v", existing, wantGrouping)
+	}
+
+	// Empty filter values match NULL values too.
+	if err := empties.RemoveFilteredPolicyCtx(ctx, "p", "p", 2, "read", ""); err != nil {
+		t.Fatalf("unable to remove filtered policy: %v", err)
+	}
+	remaining, err := empties.GetFilteredPolicy(ctx, "p", 0)
+	if err != nil {
+		t.Fatalf("unable to get policies: %v", err)
+	}
+	if len(remaining) != 0 {
+		t.Errorf("got remaining policies %v, want none", remaining)
+	}
+
+	if err := empties.RemovePoliciesCtx(ctx, "g", "g", wantGrouping); err != nil {
+		t.Fatalf("failed to remove grouping policy: %v", err)
+	}
+	if existing, err := empties.ExistingPolicies(ctx, "g", wantGrouping); err != nil || len(existing) != 0 {
+		t.Errorf("got existing policies %v, %v, want none", existing, err)
+	}
+
+	if _, err := casbun.NewAdapter(ctx, db, casbun.WithInsertIgnoreEmptyTrailing(), casbun.WithUUIDKey()); err == nil {
+		t.Errorf("expected an error combining NULL trailing values with a UUID key")
+	}
+}
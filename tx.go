@@ -0,0 +1,116 @@
+package casbun
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/uptrace/bun"
+)
+
+// txKey is the context key under which WithTx stores a transaction.
+type txKey struct{}
+
+// WithTx returns a copy of ctx carrying tx.
+// Adapter methods called with the returned context run their statements on tx
+// instead of on the adapter's database, so policy changes commit or roll back
+// together with the caller's own writes.
+//
+// Example:
+//
+//	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+//	    if _, err := tx.NewInsert().Model(&user).Exec(ctx); err != nil {
+//	        return err
+//	    }
+//	    return adapter.AddPolicyCtx(casbun.WithTx(ctx, tx), "g", "g", []string{user.Name, "member"})
+//	})
+func WithTx(ctx context.Context, tx bun.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// WithTxOptions sets the options of the transactions started by the adapter,
+// such as table creation or the batch updates of UpdatePolicies, for instance
+// to run them at the serializable isolation level. A read-only option makes
+// every write fail, so table creation should then be disabled with
+// DisableAutoCreateTable.
+//
+// Methods called with a context carrying a transaction run on a savepoint of
+// it, whose options are set by the caller.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithTxOptions(sql.TxOptions{Isolation: sql.LevelSerializable}))
+func WithTxOptions(opts sql.TxOptions) CasbinBunOption {
+	return func(a *Adapter) {
+		a.txOpts = opts
+	}
+}
+
+// txOptions returns the options of the transactions started by the adapter.
+func (a *Adapter) txOptions() *sql.TxOptions {
+	opts := a.txOpts
+	return &opts
+}
+
+// txFromContext returns the transaction carried by ctx, if any.
+func txFromContext(ctx context.Context) (bun.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(bun.Tx)
+	return tx, ok
+}
+
+// WithTx returns a copy of the adapter running every statement on tx, so that
+// a series of policy changes commits or rolls back with the caller's
+// transaction without carrying it in each context. A transaction carried by
+// the context still takes precedence. The copy must not be used once tx ends.
+//
+// Example:
+//
+//	err := db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+//	    txAdapter := adapter.WithTx(tx)
+//	    if err := txAdapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data2", "read"}); err != nil {
+//	        return err
+//	    }
+//	    return txAdapter.RemovePolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"})
+//	})
+func (a *Adapter) WithTx(tx bun.Tx) *Adapter {
+	bound := *a
+	bound.tx = &tx
+	bound.root = a
+	if a.root != nil {
+		bound.root = a.root
+	}
+	return &bound
+}
+
+// txFor returns the transaction carried by ctx, or else the transaction bound
+// by WithTx, if any.
+func (a *Adapter) txFor(ctx context.Context) (bun.Tx, bool) {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx, true
+	}
+	if a.tx != nil {
+		return *a.tx, true
+	}
+	return bun.Tx{}, false
+}
+
+// conn returns the transaction carried by ctx or bound by WithTx, or the
+// adapter's database if there is none.
+func (a *Adapter) conn(ctx context.Context) bun.IDB {
+	if tx, ok := a.txFor(ctx); ok {
+		return tx
+	}
+	return a.db
+}
+
+// reader returns the connection used for read-only queries: the transaction
+// carried by ctx or bound by WithTx if any, so that reads observe its uncommitted writes, then the
+// read database configured with WithReadDB, and finally the primary database.
+func (a *Adapter) reader(ctx context.Context) bun.IDB {
+	if tx, ok := a.txFor(ctx); ok {
+		return tx
+	}
+	if a.readDB != nil {
+		return a.readDB
+	}
+	return a.db
+}
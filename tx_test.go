@@ -0,0 +1,263 @@
+package casbun_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/driver/sqliteshim"
+)
+
+type user struct {
+	bun.BaseModel `bun:"users"`
+	ID            int64  `bun:"id,pk,autoincrement"`
+	Name          string `bun:"name"`
+}
+
+func TestWithTxRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initDB()
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	if _, err := db.NewCreateTable().Model((*user)(nil)).Exec(ctx); err != nil {
+		t.Fatalf("unable to create users table: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err = db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(&user{Name: "alice"}).Exec(ctx); err != nil {
+			return err
+		}
+		if err := adapter.AddPolicyCtx(casbun.WithTx(ctx, tx), "g", "g", []string{"alice", "admin"}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("unexpected transaction error: %v", err)
+	}
+
+	users, err := db.NewSelect().Model((*user)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count users: %v", err)
+	}
+	if users != 0 {
+		t.Errorf("got %d users, want 0", users)
+	}
+
+	policies, err := db.NewSelect().Model((*casbun.CasbinPolicy)(nil)).Count(ctx)
+	if err != nil {
+		t.Fatalf("unable to count policies: %v", err)
+	}
+	if policies != 0 {
+		t.Errorf("got %d policies, want 0", policies)
+	}
+}
+
+func TestAdapterWithTx(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+	adapter, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	kept := []string{"alice", "data1", "read"}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", kept); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err = db.RunInTx(ctx, &sql.TxOptions{}, func(ctx context.Context, tx bun.Tx) error {
+		txAdapter := adapter.WithTx(tx)
+		if err := txAdapter.AddPolicyCtx(ctx, "p", "p", []string{"bob", "data2", "write"}); err != nil {
+			return err
+		}
+		if err := txAdapter.RemovePolicy("p", "p", kept); err != nil {
+			return err
+		}
+
+		// The bound adapter observes its uncommitted changes.
+		got, err := txAdapter.GetFilteredPolicy(ctx, "p", 0)
+		if err != nil {
+			return err
+		}
+		if want := [][]string{{"p", "bob", "data2", "write"}}; !util.Array2DEquals(want, got) {
+			t.Errorf("got policies %v in the transaction, want %v", got, want)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("unexpected transaction error: %v", err)
+	}
+
+	got, err := adapter.GetFilteredPolicy(ctx, "p", 0)
+	if err != nil {
+		t.Fatalf("unable to get policies: %v", err)
+	}
+	if want := [][]string{append([]string{"p"}, kept...)}; !util.Array2DEquals(want, got) {
+		t.Errorf("got policies %v after rollback, want %v", got, want)
+	}
+}
+
+// txOptionsConnector opens sqlite connections recording the options of their
+// transactions. SQLite ignores read-only transactions, so they are emulated
+// with the query_only pragma.
+type txOptionsConnector struct {
+	driver driver.Driver
+	dsn    string
+
+	mu   sync.Mutex
+	opts []driver.TxOptions
+}
+
+func (c *txOptionsConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &txOptionsConn{Conn: conn, connector: c}, nil
+}
+
+func (c *txOptionsConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type txOptionsConn struct {
+	driver.Conn
+	connector *txOptionsConnector
+}
+
+func (c *txOptionsConn) ExecContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *txOptionsConn) QueryContext(
+	ctx context.Context,
+	query string,
+	args []driver.NamedValue,
+) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *txOptionsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.connector.mu.Lock()
+	c.connector.opts = append(c.connector.opts, opts)
+	c.connector.mu.Unlock()
+
+	if opts.ReadOnly {
+		if _, err := c.ExecContext(ctx, "PRAGMA query_only = ON", nil); err != nil {
+			return nil, err
+		}
+	}
+	tx, err := c.Conn.(driver.ConnBeginTx).BeginTx(ctx, driver.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return &txOptionsTx{Tx: tx, conn: c, readOnly: opts.ReadOnly}, nil
+}
+
+type txOptionsTx struct {
+	driver.Tx
+	conn     *txOptionsConn
+	readOnly bool
+}
+
+func (tx *txOptionsTx) Commit() error {
+	return errors.Join(tx.Tx.Commit(), tx.reset())
+}
+
+func (tx *txOptionsTx) Rollback() error {
+	return errors.Join(tx.Tx.Rollback(), tx.reset())
+}
+
+func (tx *txOptionsTx) reset() error {
+	if !tx.readOnly {
+		return nil
+	}
+	_, err := tx.conn.ExecContext(context.Background(), "PRAGMA query_only = OFF", nil)
+	return err
+}
+
+func TestWithTxOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sqldb, err := sql.Open(sqliteshim.ShimName, "")
+	if err != nil {
+		t.Fatalf("unable to open database: %v", err)
+	}
+	connector := &txOptionsConnector{
+		driver: sqldb.Driver(),
+		dsn:    "file:" + filepath.Join(t.TempDir(), "casbin.db") + "?_pragma=busy_timeout(5000)",
+	}
+	_ = sqldb.Close()
+
+	db := bun.NewDB(sql.OpenDB(connector), sqlitedialect.New())
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	writer, err := casbun.NewAdapter(ctx, db)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	rule := []string{"alice", "data1", "read"}
+	if err := writer.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+		t.Fatalf("unable to add policy: %v", err)
+	}
+
+	adapter, err := casbun.NewAdapter(
+		ctx,
+		db,
+		casbun.DisableAutoCreateTable(),
+		casbun.WithTxOptions(sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	connector.mu.Lock()
+	connector.opts = nil
+	connector.mu.Unlock()
+
+	if err := adapter.RemovePoliciesCtx(ctx, "p", "p", [][]string{rule}); err == nil {
+		t.Fatal("expected an error removing policies in a read-only transaction")
+	}
+
+	connector.mu.Lock()
+	opts := connector.opts
+	connector.mu.Unlock()
+	if len(opts) != 1 {
+		t.Fatalf("got %d transactions, want 1", len(opts))
+	}
+	if !opts[0].ReadOnly || sql.IsolationLevel(opts[0].Isolation) != sql.LevelSerializable {
+		t.Errorf("got transaction options %+v, want read-only serializable", opts[0])
+	}
+
+	policies, err := writer.GetFilteredPolicy(ctx, "p", 0)
+	if err != nil {
+		t.Fatalf("unable to get policies: %v", err)
+	}
+	if len(policies) != 1 {
+		t.Errorf("got %d policies, want 1", len(policies))
+	}
+}
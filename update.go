@@ -0,0 +1,140 @@
+package casbun
+
+import (
+	"context"
+
+	"github.com/uptrace/bun"
+)
+
+// UpdateStrategy defines how UpdatePolicies rewrites the stored rules.
+type UpdateStrategy int
+
+const (
+	// UpdateInPlace rewrites the old rules into the new ones with UPDATE
+	// statements, keeping the ids and the other columns of the rows. The rules
+	// of a cyclic mapping, such as A to B and B to A, are replaced like with
+	// UpdateDeleteInsert instead. This is the default strategy.
+	UpdateInPlace UpdateStrategy = iota
+	// UpdateDeleteInsert deletes every old rule with a single statement, then
+	// inserts every new rule in bulk, in a single transaction. It suits models
+	// where updating a rule means replacing it, and is faster for large sets,
+	// but the new rules are stored in new rows: their ids change and the
+	// columns outside the rule, such as the metadata, are reset.
+	UpdateDeleteInsert
+)
+
+// WithUpdateStrategy sets the strategy used by UpdatePolicies to rewrite the
+// stored rules. The default is UpdateInPlace.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithUpdateStrategy(UpdateDeleteInsert))
+func WithUpdateStrategy(strategy UpdateStrategy) CasbinBunOption {
+	return func(a *Adapter) {
+		a.updateStrategy = strategy
+	}
+}
+
+// replacePolicyRecords deletes oldPolicies and inserts newPolicies in tx, all
+// of the same ptype.
+func (a *Adapter) replacePolicyRecords(
+	ctx context.Context,
+	tx bun.Tx,
+	oldPolicies, newPolicies []CasbinPolicy,
+) error {
+	if len(oldPolicies) == 0 {
+		return nil
+	}
+
+	tuples := make([][]string, 0, len(oldPolicies))
+	for _, policy := range oldPolicies {
+		tuples = append(tuples, policy.values())
+	}
+	if _, err := a.newDelete(ctx, tx).
+		Where("? = ?", ptypeColumn, oldPolicies[0].PType).
+		// Unused columns may hold NULL, see WithInsertIgnoreEmptyTrailing.
+		Where("(COALESCE(?, ''), COALESCE(?, ''), COALESCE(?, ''), "+
+			"COALESCE(?, ''), COALESCE(?, ''), COALESCE(?, '')) IN (?)",
+			valueColumn(0), valueColumn(1), valueColumn(2),
+			valueColumn(3), valueColumn(4), valueColumn(5), bun.In(tuples)).
+		Exec(ctx); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(newPolicies); start += insertBatchSize {
+		batch := newPolicies[start:min(start+insertBatchSize, len(newPolicies))]
+		if _, err := a.newInsert(ctx, tx, &batch).Exec(ctx); err != nil {
+			return insertError(err)
+		}
+	}
+	return nil
+}
+
+// updateOrder returns the order in which the old rules can be rewritten one
+// at a time when the mapping is chained, such as A to B and B to C, or maps
+// the same old rule twice, which a single statement or independent batches
+// can not apply. A rule is rewritten after the rule it replaces has moved
+// away. chained is false if the rules can be rewritten in any order, and
+// order is nil if the mapping is a cycle, such as A to B and B to A.
+func updateOrder(oldPolicies, newPolicies []CasbinPolicy) (order []int, chained bool) {
+	pending := make(map[[7]string]int, len(oldPolicies))
+	for _, policy := range oldPolicies {
+		pending[policy.key()]++
+	}
+	for i, policy := range newPolicies {
+		key := policy.key()
+		if pending[oldPolicies[i].key()] > 1 || (pending[key] > 0 && key != oldPolicies[i].key()) {
+			chained = true
+			break
+		}
+	}
+	if !chained {
+		return nil, false
+	}
+
+	// blocked reports whether the new rule of i is still held by another
+	// pending old rule.
+	blocked := func(i int) bool {
+		key := newPolicies[i].key()
+		held := pending[key]
+		if oldPolicies[i].key() == key {
+			held--
+		}
+		return held > 0
+	}
+
+	order = make([]int, 0, len(oldPolicies))
+	done := make([]bool, len(oldPolicies))
+	for len(order) < len(oldPolicies) {
+		progress := false
+		for i := range oldPolicies {
+			if done[i] || blocked(i) {
+				continue
+			}
+			done[i] = true
+			pending[oldPolicies[i].key()]--
+			order = append(order, i)
+			progress = true
+		}
+		if !progress {
+			return nil, true
+		}
+	}
+	return order, true
+}
+
+// updateRecordsInOrder rewrites the old rules into the new ones in tx, one at
+// a time in the given order, see updateOrder.
+func (a *Adapter) updateRecordsInOrder(
+	ctx context.Context,
+	tx bun.Tx,
+	oldPolicies, newPolicies []CasbinPolicy,
+	order []int,
+) error {
+	for _, i := range order {
+		if err := a.update(ctx, a.newRuleUpdate(ctx, tx, newPolicies[i]), oldPolicies[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package casbun_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/casbin/casbin/v2/util"
+	"github.com/mmikalsen/casbun"
+)
+
+func TestUpdateStrategy(t *testing.T) {
+	t.Parallel()
+
+	policies := [][]string{
+		{"alice", "data1", "write"},
+		{"bob", "data1", "read"},
+		{"alice", "data2", "write"},
+		{"carol", "data3", "read"},
+	}
+	oldRules := [][]string{policies[0], policies[2], policies[3]}
+	newRules := [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "read"},
+		{"carol", "data3", "write"},
+	}
+
+	results := make(map[casbun.UpdateStrategy]map[string][][]string)
+	for _, strategy := range []casbun.UpdateStrategy{casbun.UpdateInPlace, casbun.UpdateDeleteInsert} {
+		ctx := context.Background()
+		adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithUpdateStrategy(strategy))
+		if err != nil {
+			t.Fatalf("unable to create adapter: %v", err)
+		}
+
+		if err := adapter.AddPoliciesCtx(ctx, "p", "p", policies); err != nil {
+			t.Fatalf("failed to add policies: %v", err)
+		}
+		if err := adapter.UpdatePoliciesCtx(ctx, "p", "p", oldRules, newRules); err != nil {
+			t.Fatalf("failed to update policies with strategy %d: %v", strategy, err)
+		}
+
+		rules, err := adapter.LoadPolicyArray(ctx)
+		if err != nil {
+			t.Fatalf("failed to load policies: %v", err)
+		}
+		results[strategy] = rules
+	}
+
+	want := [][]string{
+		{"alice", "data1", "read"},
+		{"alice", "data2", "read"},
+		{"bob", "data1", "read"},
+		{"carol", "data3", "write"},
+	}
+	for strategy, rules := range results {
+		got := rules["p"]
+		util.SortArray2D(got)
+		if !util.Array2DEquals(got, want) {
+			t.Errorf("got rules %v with strategy %d, want %v", got, strategy, want)
+		}
+	}
+}
+
+func TestUpdatePoliciesChained(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		oldRules [][]string
+		newRules [][]string
+		want     [][]string
+		keepIDs  bool
+	}{
+		{
+			name:     "chain",
+			oldRules: [][]string{{"alice", "data1", "read"}, {"bob", "data1", "read"}},
+			newRules: [][]string{{"bob", "data1", "read"}, {"carol", "data1", "read"}},
+			want:     [][]string{{"bob", "data1", "read"}, {"carol", "data1", "read"}},
+			keepIDs:  true,
+		},
+		{
+			name:     "cycle",
+			oldRules: [][]string{{"alice", "data1", "read"}, {"bob", "data1", "read"}},
+			newRules: [][]string{{"bob", "data1", "read"}, {"alice", "data1", "read"}},
+			want:     [][]string{{"alice", "data1", "read"}, {"bob", "data1", "read"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			adapter, err := casbun.NewAdapter(ctx, initDB())
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+			if err := adapter.AddPoliciesCtx(ctx, "p", "p", tt.oldRules); err != nil {
+				t.Fatalf("failed to add policies: %v", err)
+			}
+			before, err := adapter.LoadPolicyWithIDs(ctx)
+			if err != nil {
+				t.Fatalf("failed to load policies: %v", err)
+			}
+
+			if err := adapter.UpdatePoliciesCtx(ctx, "p", "p", tt.oldRules, tt.newRules); err != nil {
+				t.Fatalf("failed to update policies: %v", err)
+			}
+
+			rules, err := adapter.LoadPolicyArray(ctx)
+			if err != nil {
+				t.Fatalf("failed to load policies: %v", err)
+			}
+			got := rules["p"]
+			util.SortArray2D(got)
+			if !util.Array2DEquals(got, tt.want) {
+				t.Errorf("got rules %v, want %v", got, tt.want)
+			}
+
+			if !tt.keepIDs {
+				return
+			}
+			after, err := adapter.LoadPolicyWithIDs(ctx)
+			if err != nil {
+				t.Fatalf("failed to load policies: %v", err)
+			}
+			for i, rule := range tt.oldRules {
+				id := findID(before, rule)
+				if got := findID(after, tt.newRules[i]); got != id {
+					t.Errorf("got id %d for %v, want the id %d of %v", got, tt.newRules[i], id, rule)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdatePoliciesLengthMismatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB())
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	oldRules := [][]string{{"alice", "data1", "read"}, {"bob", "data1", "read"}}
+	if err := adapter.AddPoliciesCtx(ctx, "p", "p", oldRules); err != nil {
+		t.Fatalf("failed to add policies: %v", err)
+	}
+
+	if err := adapter.UpdatePoliciesCtx(ctx, "p", "p", oldRules, oldRules[:1]); err == nil {
+		t.Error("expected an error updating 2 rules into 1")
+	}
+}
+
+// findID returns the id of rule among policies, or 0 if it is missing.
+func findID(policies []casbun.PolicyWithID, rule []string) int64 {
+	for _, policy := range policies {
+		if slices.Equal(policy.Rule, rule) {
+			return policy.ID
+		}
+	}
+	return 0
+}
@@ -0,0 +1,27 @@
+package casbun
+
+import (
+	"github.com/google/uuid"
+)
+
+// WithUUIDKey keys the policy table on a UUID id generated by the adapter on
+// insert, instead of an autoincrement id. This keeps ids unique across
+// databases, e.g. for replication, and avoids contention on the sequence.
+// Rules are still matched by ptype and values, and CasbinPolicy.ID is left
+// zero on rows read back in this mode. It can not be combined with
+// WithCompositeKey. Combined with WithIDGenerator, the ids come from its
+// generator instead.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithUUIDKey())
+func WithUUIDKey() CasbinBunOption {
+	return func(a *Adapter) {
+		a.uuidKey = true
+	}
+}
+
+// newUUID is the id generator of an adapter using WithUUIDKey.
+func newUUID() interface{} {
+	return uuid.NewString()
+}
@@ -0,0 +1,240 @@
+package casbun
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// ValidateSchema checks that the policy table matches the schema expected by
+// the adapter, and returns an error wrapping ErrSchemaMismatch that lists every
+// discrepancy otherwise: missing columns, columns of an unexpected type and a
+// missing unique index on the rule columns. It is meant to be called at startup
+// when DisableAutoCreateTable is used, to catch misconfigured migrations before
+// they cause confusing errors at runtime.
+//
+// ValidateSchema supports SQLite, PostgreSQL and MySQL.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, DisableAutoCreateTable())
+//	if err != nil {
+//	    return err
+//	}
+//	if err := adapter.ValidateSchema(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+func (a *Adapter) ValidateSchema(ctx context.Context) (err error) {
+	defer wrapOpError("validate_schema", "", &err)
+	columns, err := a.tableColumns(ctx)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: table %q does not exist", ErrSchemaMismatch, a.tableName)
+	}
+
+	var problems []string
+	switch {
+	case a.hasSerialID() || a.uuidKey:
+		problems = append(problems, checkColumn(columns, "id", a.uuidKey)...)
+	case a.idGenerator != nil:
+		// Generated ids may be stored in a column of any type.
+		if _, ok := columns["id"]; !ok {
+			problems = append(problems, "missing column id")
+		}
+	}
+	for _, col := range a.Columns() {
+		problems = append(problems, checkColumn(columns, col, true)...)
+	}
+
+	indexes, err := a.uniqueIndexes(ctx)
+	if err != nil {
+		return err
+	}
+	if columns := a.uniqueIndexedColumns(); !a.noUniqueIndex && !hasRuleIndex(indexes, columns) {
+		problems = append(problems, fmt.Sprintf("no unique index on (%s)", strings.Join(columns, ", ")))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: table %q: %s", ErrSchemaMismatch, a.tableName, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// NewAdapterFromExistingTable creates a new Casbin policy adapter like
+// NewAdapter for a policy table managed outside the adapter, e.g. by
+// migrations. It issues no DDL, but validates the table with ValidateSchema
+// and fails with an error wrapping ErrSchemaMismatch if the table is missing
+// or malformed, instead of failing later on the first query.
+//
+// Example:
+//
+//	adapter, err := NewAdapterFromExistingTable(ctx, db, WithTableName("auth.policies"))
+//	if err != nil {
+//	    log.Fatal("Invalid policy table:", err)
+//	}
+func NewAdapterFromExistingTable(ctx context.Context, db *bun.DB, opts ...CasbinBunOption) (*Adapter, error) {
+	a, err := NewAdapterWithoutInit(db, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.ValidateSchema(ctx); err != nil {
+		// The caller keeps db, which the discarded adapter must not close.
+		runtime.SetFinalizer(a, nil)
+		return nil, err
+	}
+	return a, nil
+}
+
+// checkColumn reports the problems of the column col among columns, which map
+// the column names to their types. A textual column must be of a character
+// type, and any other one of an integer type.
+func checkColumn(columns map[string]string, col string, textual bool) []string {
+	typ, ok := columns[col]
+	if !ok {
+		return []string{fmt.Sprintf("missing column %s", col)}
+	}
+
+	typ = strings.ToLower(typ)
+	if textual && !strings.Contains(typ, "char") && !strings.Contains(typ, "text") && typ != "uuid" {
+		return []string{fmt.Sprintf("column %s has type %s, want a character type", col, typ)}
+	}
+	if !textual && !strings.Contains(typ, "int") && typ != "serial" {
+		return []string{fmt.Sprintf("column %s has type %s, want an integer type", col, typ)}
+	}
+	return nil
+}
+
+// hasRuleIndex reports whether one of indexes, which map the unique index
+// names to their columns, covers exactly columns.
+func hasRuleIndex(indexes map[string][]string, columns []string) bool {
+	want := append([]string(nil), columns...)
+	sort.Strings(want)
+
+	for _, indexColumns := range indexes {
+		got := make([]string, 0, len(indexColumns))
+		for _, col := range indexColumns {
+			got = append(got, strings.ToLower(col))
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, ",") == strings.Join(want, ",") {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTableName splits the schema-qualified table name of the policies into
+// its schema, empty if unqualified, and its table.
+func (a *Adapter) splitTableName() (schema, table string) {
+	if i := strings.LastIndex(a.tableName, "."); i >= 0 {
+		return a.tableName[:i], a.tableName[i+1:]
+	}
+	return "", a.tableName
+}
+
+// tableColumns returns the types of the columns of the policy table by name.
+// The map is empty if the table does not exist.
+func (a *Adapter) tableColumns(ctx context.Context) (map[string]string, error) {
+	schema, table := a.splitTableName()
+
+	var query string
+	args := []interface{}{table}
+	switch name := a.dialect().name; name {
+	case dialect.SQLite:
+		query = "SELECT name, type FROM pragma_table_info(?)"
+		if schema != "" {
+			query = "SELECT name, type FROM pragma_table_info(?, ?)"
+			args = append(args, schema)
+		}
+	case dialect.PG:
+		query = "SELECT column_name, data_type FROM information_schema.columns " +
+			"WHERE table_name = ? AND table_schema = COALESCE(?, current_schema())"
+		args = append(args, nullString(schema))
+	case dialect.MySQL:
+		query = "SELECT column_name, data_type FROM information_schema.columns " +
+			"WHERE table_name = ? AND table_schema = COALESCE(?, DATABASE())"
+		args = append(args, nullString(schema))
+	default:
+		return nil, fmt.Errorf("casbun: schema validation is not supported on %s", name)
+	}
+
+	rows, err := a.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, typ string
+		if err := rows.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		columns[strings.ToLower(name)] = typ
+	}
+	return columns, rows.Err()
+}
+
+// uniqueIndexes returns the columns of the unique indexes of the policy table,
+// including its primary key, by index name.
+func (a *Adapter) uniqueIndexes(ctx context.Context) (map[string][]string, error) {
+	schema, table := a.splitTableName()
+
+	var query string
+	args := []interface{}{table}
+	switch name := a.dialect().name; name {
+	case dialect.SQLite:
+		query = `SELECT il.name, ii.name FROM pragma_index_list(?) AS il ` +
+			`JOIN pragma_index_info(il.name) AS ii WHERE il."unique" = 1`
+		if schema != "" {
+			query = `SELECT il.name, ii.name FROM pragma_index_list(?, ?) AS il ` +
+				`JOIN pragma_index_info(il.name, ?) AS ii WHERE il."unique" = 1`
+			args = append(args, schema, schema)
+		}
+	case dialect.PG:
+		query = "SELECT i.relname, att.attname FROM pg_index AS x " +
+			"JOIN pg_class AS i ON i.oid = x.indexrelid " +
+			"JOIN pg_attribute AS att ON att.attrelid = x.indrelid AND att.attnum = ANY(x.indkey) " +
+			"WHERE x.indisunique AND x.indrelid = ?::regclass"
+		args = []interface{}{a.tableName}
+	case dialect.MySQL:
+		query = "SELECT index_name, column_name FROM information_schema.statistics " +
+			"WHERE table_name = ? AND table_schema = COALESCE(?, DATABASE()) AND non_unique = 0"
+		args = append(args, nullString(schema))
+	default:
+		return nil, fmt.Errorf("casbun: schema validation is not supported on %s", name)
+	}
+
+	rows, err := a.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]string)
+	for rows.Next() {
+		var index, column string
+		if err := rows.Scan(&index, &column); err != nil {
+			return nil, err
+		}
+		indexes[index] = append(indexes[index], column)
+	}
+	return indexes, rows.Err()
+}
+
+// nullString returns nil for an empty s, to be formatted as NULL.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,162 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestValidateSchema(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		setup   string
+		opts    []casbun.CasbinBunOption
+		wantErr string
+	}{
+		{
+			name: "created by the adapter",
+		},
+		{
+			name: "composite key",
+			opts: []casbun.CasbinBunOption{casbun.WithCompositeKey()},
+		},
+		{
+			name: "uuid key",
+			opts: []casbun.CasbinBunOption{casbun.WithUUIDKey()},
+		},
+		{
+			name: "id generator",
+			opts: []casbun.CasbinBunOption{casbun.WithIDGenerator(func() interface{} { return "rule-1" })},
+		},
+		{
+			name: "id generator with an integer id",
+			setup: `CREATE TABLE casbin_policies (
+				id BIGINT PRIMARY KEY,
+				ptype VARCHAR(100), v0 VARCHAR(100), v1 VARCHAR(100), v2 VARCHAR(100),
+				v3 VARCHAR(100), v4 VARCHAR(100), v5 VARCHAR(100),
+				UNIQUE (ptype, v0, v1, v2, v3, v4, v5)
+			)`,
+			opts: []casbun.CasbinBunOption{
+				casbun.DisableAutoCreateTable(),
+				casbun.WithIDGenerator(func() interface{} { return int64(1) }),
+			},
+		},
+		{
+			name: "missing v5",
+			setup: `CREATE TABLE casbin_policies (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				ptype VARCHAR(100), v0 VARCHAR(100), v1 VARCHAR(100),
+				v2 VARCHAR(100), v3 VARCHAR(100), v4 VARCHAR(100)
+			)`,
+			opts:    []casbun.CasbinBunOption{casbun.DisableAutoCreateTable()},
+			wantErr: `missing column v5; no unique index on (ptype, v0, v1, v2, v3, v4, v5)`,
+		},
+		{
+			name: "wrong type",
+			setup: `CREATE TABLE casbin_policies (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				ptype VARCHAR(100), v0 INTEGER, v1 VARCHAR(100), v2 VARCHAR(100),
+				v3 VARCHAR(100), v4 VARCHAR(100), v5 VARCHAR(100),
+				UNIQUE (ptype, v0, v1, v2, v3, v4, v5)
+			)`,
+			opts:    []casbun.CasbinBunOption{casbun.DisableAutoCreateTable()},
+			wantErr: "column v0 has type integer, want a character type",
+		},
+		{
+			name:    "missing table",
+			opts:    []casbun.CasbinBunOption{casbun.DisableAutoCreateTable()},
+			wantErr: `table "casbin_policies" does not exist`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			db := initFileDB(t)
+			if tt.setup != "" {
+				if _, err := db.ExecContext(ctx, tt.setup); err != nil {
+					t.Fatalf("unable to create table: %v", err)
+				}
+			}
+
+			adapter, err := casbun.NewAdapter(ctx, db, tt.opts...)
+			if err != nil {
+				t.Fatalf("unable to create adapter: %v", err)
+			}
+
+			err = adapter.ValidateSchema(ctx)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, casbun.ErrSchemaMismatch) {
+				t.Fatalf("got error %v, want %v", err, casbun.ErrSchemaMismatch)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAdapterFromExistingTable(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+
+	_, err := casbun.NewAdapterFromExistingTable(ctx, db, casbun.WithTableName("auth_policies"))
+	if !errors.Is(err, casbun.ErrSchemaMismatch) {
+		t.Fatalf("got error %v, want %v", err, casbun.ErrSchemaMismatch)
+	}
+	if want := `table "auth_policies" does not exist`; !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q, want it to contain %q", err, want)
+	}
+
+	// The adapter creating the table is kept alive until the end, as its
+	// finalizer closes db.
+	creator, err := casbun.NewAdapter(ctx, db, casbun.WithTableName("auth_policies"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	adapter, err := casbun.NewAdapterFromExistingTable(ctx, db, casbun.WithTableName("auth_policies"))
+	if err != nil {
+		t.Fatalf("unable to create adapter from the existing table: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Errorf("failed to add policy: %v", err)
+	}
+	runtime.KeepAlive(creator)
+}
+
+func TestNewAdapterFromExistingTableIDGenerator(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	db := initFileDB(t)
+	generate := func() interface{} { return "rule-1" }
+
+	// The adapter creating the table is kept alive until the end, as its
+	// finalizer closes db.
+	creator, err := casbun.NewAdapter(ctx, db, casbun.WithIDGenerator(generate))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+	adapter, err := casbun.NewAdapterFromExistingTable(ctx, db, casbun.WithIDGenerator(generate))
+	if err != nil {
+		t.Fatalf("unable to create adapter from the existing table: %v", err)
+	}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", []string{"alice", "data1", "read"}); err != nil {
+		t.Errorf("failed to add policy: %v", err)
+	}
+	runtime.KeepAlive(creator)
+}
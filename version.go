@@ -0,0 +1,153 @@
+package casbun
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// WithVersionColumn adds the integer column col to the policy table, holding
+// the version of each rule. It starts at 0 and is incremented each time the
+// rule is rewritten by UpdatePolicy or UpdatePolicies with UpdateInPlace,
+// which lets admin UIs detect lost updates with GetVersion and
+// UpdatePolicyIfVersion. Rules rewritten by deleting and inserting them, such
+// as by UpdateFilteredPolicies, start over at version 0.
+//
+// The column is created with the table, so an existing table must be altered
+// to add it. NewAdapter fails if col is empty.
+//
+// Example:
+//
+//	adapter, err := NewAdapter(ctx, db, WithVersionColumn("version"))
+func WithVersionColumn(col string) CasbinBunOption {
+	return func(a *Adapter) {
+		if col == "" {
+			a.optionErr = errors.Join(a.optionErr, errors.New("casbun: version column must not be empty"))
+			return
+		}
+		a.versionColumn = col
+	}
+}
+
+// GetVersion returns the version stored with a policy rule of ptype in the
+// column set by WithVersionColumn. It returns sql.ErrNoRows if the rule is not
+// stored.
+//
+// Example:
+//
+//	version, err := adapter.GetVersion(ctx, "p", []string{"alice", "data1", "read"})
+func (a *Adapter) GetVersion(ctx context.Context, ptype string, rule []string) (_ int, err error) {
+	defer wrapOpError("get_version", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.GetVersion(ctx, ptype, rule)
+	}
+	defer a.observe("get_version")()
+
+	if err := a.flushWrites(ctx); err != nil {
+		return 0, err
+	}
+
+	if a.versionColumn == "" {
+		return 0, errors.New("casbun: no version column, see WithVersionColumn")
+	}
+
+	var version int
+	if err := a.reader(ctx).NewSelect().
+		Comment(a.queryComment(ctx)).
+		ModelTableExpr("?", bun.Ident(a.tableName)).
+		ColumnExpr("?", bun.Ident(a.versionColumn)).
+		ApplyQueryBuilder(keyCondition(newCasbinPolicy(ptype, rule))).
+		Scan(ctx, &version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// UpdatePolicyIfVersion updates a policy rule like UpdatePolicyCtx, provided
+// its stored version, as returned by GetVersion, is still expectedVersion. It
+// fails with an error wrapping ErrVersionConflict otherwise, including when
+// oldRule is no longer stored, so that an edit based on a stale read is not
+// silently applied.
+//
+// Example:
+//
+//	err := adapter.UpdatePolicyIfVersion(ctx, "p", oldRule, newRule, version)
+//	if errors.Is(err, casbun.ErrVersionConflict) {
+//	    http.Error(w, "the policy was changed by someone else", http.StatusConflict)
+//	}
+func (a *Adapter) UpdatePolicyIfVersion(
+	ctx context.Context,
+	ptype string,
+	oldRule, newRule []string,
+	expectedVersion int,
+) (err error) {
+	defer wrapOpError("update_policy_if_version", ptype, &err)
+	if r := a.route(ptype); r != a {
+		return r.UpdatePolicyIfVersion(ctx, ptype, oldRule, newRule, expectedVersion)
+	}
+	defer a.observe("update_policy_if_version")()
+
+	if a.versionColumn == "" {
+		return errors.New("casbun: no version column, see WithVersionColumn")
+	}
+	if err := a.validateRules(ptype, [][]string{newRule}); err != nil {
+		return err
+	}
+	event := PolicyEvent{
+		Op:       EventUpdate,
+		PType:    ptype,
+		Rules:    [][]string{newRule},
+		OldRules: [][]string{oldRule},
+	}
+	if err := a.before(ctx, event); err != nil {
+		return err
+	}
+
+	oldPolicy := newCasbinPolicy(ptype, oldRule)
+	newPolicy := newCasbinPolicy(ptype, newRule)
+	err = a.retry(ctx, func(ctx context.Context) error {
+		res, err := a.newRuleUpdate(ctx, a.conn(ctx), newPolicy).
+			ApplyQueryBuilder(keyCondition(oldPolicy)).
+			Where("? = ?", bun.Ident(a.versionColumn), expectedVersion).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("%w: rule of %s is not stored at version %d", ErrVersionConflict, ptype, expectedVersion)
+		}
+		return nil
+	})
+	return a.notify(ctx, err, event)
+}
+
+// newRuleUpdate returns a query rewriting policy rows into the rule of
+// policy, which also increments their version when WithVersionColumn is used.
+func (a *Adapter) newRuleUpdate(ctx context.Context, db bun.IDB, policy CasbinPolicy) *bun.UpdateQuery {
+	if a.versionColumn == "" {
+		return a.newUpdate(ctx, db, &policy)
+	}
+
+	// Set replaces the columns of the model, so the rule is set explicitly.
+	query := a.newUpdate(ctx, db, (*CasbinPolicy)(nil)).
+		Set("? = ?", ptypeColumn, policy.PType)
+	for i, v := range policy.values() {
+		query = query.Set("? = ?", valueColumn(i), v)
+	}
+	return a.incrementVersion(query)
+}
+
+// incrementVersion adds to query the increment of the version of the updated
+// rows, if WithVersionColumn is used.
+func (a *Adapter) incrementVersion(query *bun.UpdateQuery) *bun.UpdateQuery {
+	if a.versionColumn == "" {
+		return query
+	}
+	return query.Set("? = ? + 1", bun.Ident(a.versionColumn), bun.Ident(a.versionColumn))
+}
@@ -0,0 +1,52 @@
+package casbun_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mmikalsen/casbun"
+)
+
+func TestUpdatePolicyIfVersion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	adapter, err := casbun.NewAdapter(ctx, initDB(), casbun.WithVersionColumn("version"))
+	if err != nil {
+		t.Fatalf("unable to create adapter: %v", err)
+	}
+
+	rule := []string{"alice", "data1", "read"}
+	if err := adapter.AddPolicyCtx(ctx, "p", "p", rule); err != nil {
+		t.Fatalf("failed to add policy: %v", err)
+	}
+	stale, err := adapter.GetVersion(ctx, "p", rule)
+	if err != nil {
+		t.Fatalf("unable to get version: %v", err)
+	}
+	if stale != 0 {
+		t.Errorf("got version %d, want 0", stale)
+	}
+
+	// Another editor rewrites the rule after the version was read.
+	edited := []string{"alice", "data1", "write"}
+	if err := adapter.UpdatePolicyCtx(ctx, "p", "p", rule, edited); err != nil {
+		t.Fatalf("failed to update policy: %v", err)
+	}
+	if version, err := adapter.GetVersion(ctx, "p", edited); err != nil || version != 1 {
+		t.Errorf("got version %d and error %v, want version 1", version, err)
+	}
+
+	err = adapter.UpdatePolicyIfVersion(ctx, "p", edited, []string{"alice", "data2", "read"}, stale)
+	if !errors.Is(err, casbun.ErrVersionConflict) {
+		t.Fatalf("got error %v, want %v", err, casbun.ErrVersionConflict)
+	}
+
+	if err := adapter.UpdatePolicyIfVersion(ctx, "p", edited, []string{"alice", "data2", "read"}, 1); err != nil {
+		t.Fatalf("failed to update policy at the current version: %v", err)
+	}
+	if version, err := adapter.GetVersion(ctx, "p", []string{"alice", "data2", "read"}); err != nil || version != 2 {
+		t.Errorf("got version %d and error %v, want version 2", version, err)
+	}
+}